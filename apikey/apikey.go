@@ -0,0 +1,232 @@
+// Package apikey authenticates API clients by key and enforces per-key limits.
+package apikey
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"math"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/logging"
+	"github.com/11th-ndn-hackathon/ndn-fch/ratelimit"
+	"go.uber.org/zap"
+)
+
+var logger = logging.New("apikey")
+
+// checkInterval is the minimum interval between checking the registry file for changes.
+const checkInterval = time.Second
+
+// quotaPeriod is the period of Key.Quota.
+const quotaPeriod = 24 * time.Hour
+
+// Key is an entry in the key registry.
+type Key struct {
+	Key string `json:"key"`
+	// Name identifies the key in metrics and access policy.
+	// Default is "key" followed by its index in the registry.
+	Name string `json:"name"`
+
+	// Defaults contains query parameters applied when absent from a request, such as "network=yoursunny&k=3".
+	Defaults string `json:"defaults,omitempty"`
+
+	// Rate is the sustained rate limit in requests per second; zero means unlimited.
+	Rate float64 `json:"rate,omitempty"`
+	// Burst is the number of requests allowed in a burst; default is Rate rounded up.
+	Burst float64 `json:"burst,omitempty"`
+	// Quota is the number of requests allowed per day; zero means unlimited.
+	Quota float64 `json:"quota,omitempty"`
+
+	defaults url.Values
+}
+
+// Apply returns query parameters with key defaults added.
+// A default parameter is added only if the query does not contain that parameter.
+func (k *Key) Apply(query url.Values) url.Values {
+	if k == nil || len(k.defaults) == 0 {
+		return query
+	}
+	query = maps.Clone(query)
+	for name, values := range k.defaults {
+		if _, ok := query[name]; !ok {
+			query[name] = values
+		}
+	}
+	return query
+}
+
+type registryFile struct {
+	Keys []*Key `json:"keys"`
+}
+
+// Usage contains usage counters of a key.
+type Usage struct {
+	Requests uint64 `json:"requests"` // admitted requests
+	Rejected uint64 `json:"rejected"` // requests rejected due to rate limit or quota
+}
+
+type usage struct {
+	Usage
+	rate  ratelimit.Bucket
+	quota ratelimit.Bucket
+}
+
+// Registry is a key registry file that is reloaded when it has changed.
+type Registry struct {
+	filename string
+
+	lock    sync.Mutex
+	keys    map[string]*Key // by secret key
+	usage   map[string]*usage
+	modTime time.Time
+	checked time.Time
+}
+
+// NewRegistry creates Registry from filename.
+// Returns nil if filename is empty.
+func NewRegistry(filename string) *Registry {
+	if filename == "" {
+		return nil
+	}
+	return &Registry{
+		filename: filename,
+		keys:     map[string]*Key{},
+		usage:    map[string]*usage{},
+	}
+}
+
+// reload reloads the registry file if it has changed.
+// If the file cannot be loaded, the previous keys remain in effect.
+func (r *Registry) reload(now time.Time) {
+	if now.Sub(r.checked) < checkInterval {
+		return
+	}
+	r.checked = now
+
+	st, e := os.Stat(r.filename)
+	switch {
+	case errors.Is(e, fs.ErrNotExist):
+		if len(r.keys) > 0 {
+			logger.Info("file removed")
+		}
+		r.keys, r.modTime = map[string]*Key{}, time.Time{}
+		r.updateUsage()
+		return
+	case e != nil:
+		logger.Warn("stat error", zap.Error(e))
+		return
+	case st.ModTime().Equal(r.modTime):
+		return
+	}
+
+	keys, e := load(r.filename)
+	if e != nil {
+		logger.Error("load error", zap.Error(e))
+		return
+	}
+	r.keys, r.modTime = keys, st.ModTime()
+	r.updateUsage()
+	logger.Info("load success", zap.Int("count", len(keys)))
+}
+
+// updateUsage applies limits of current keys, keeping counters and tokens of existing key names.
+func (r *Registry) updateUsage() {
+	old := r.usage
+	r.usage = map[string]*usage{}
+	for _, k := range r.keys {
+		u := old[k.Name]
+		if u == nil {
+			u = &usage{}
+		}
+		u.rate.SetLimit(k.Rate, k.Burst)
+		u.quota.SetLimit(k.Quota/quotaPeriod.Seconds(), k.Quota)
+		r.usage[k.Name] = u
+	}
+}
+
+func load(filename string) (keys map[string]*Key, e error) {
+	body, e := os.ReadFile(filename)
+	if e != nil {
+		return nil, e
+	}
+	var f registryFile
+	if e = json.Unmarshal(body, &f); e != nil {
+		return nil, e
+	}
+
+	keys = map[string]*Key{}
+	for i, k := range f.Keys {
+		if k.Key == "" {
+			return nil, fmt.Errorf("key %d is empty", i)
+		}
+		if k.Name == "" {
+			k.Name = "key" + strconv.Itoa(i)
+		}
+		if k.Rate > 0 && k.Burst <= 0 {
+			k.Burst = math.Ceil(k.Rate)
+		}
+		if k.defaults, e = url.ParseQuery(k.Defaults); e != nil {
+			return nil, fmt.Errorf("key %s has invalid defaults: %w", k.Name, e)
+		}
+		keys[k.Key] = k
+	}
+	return keys, nil
+}
+
+// Lookup finds a key by its secret value.
+// Returns nil and true if secret is empty, or there is no registry.
+// Returns nil and false if secret is not in the registry.
+func (r *Registry) Lookup(secret string) (k *Key, ok bool) {
+	if r == nil || secret == "" {
+		return nil, true
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.reload(time.Now())
+	k = r.keys[secret]
+	return k, k != nil
+}
+
+// Admit counts a request by a key and enforces its rate limit and quota.
+// Returns zero if the request is admitted, otherwise how long until it would be admitted.
+func (r *Registry) Admit(k *Key, now time.Time) (retryAfter time.Duration) {
+	if r == nil || k == nil {
+		return 0
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	u := r.usage[k.Name]
+	if u == nil {
+		return 0
+	}
+
+	if retryAfter = max(u.rate.Wait(now, 1), u.quota.Wait(now, 1)); retryAfter > 0 {
+		u.Rejected++
+		return retryAfter
+	}
+	u.rate.Take(now, 1)
+	u.quota.Take(now, 1)
+	u.Requests++
+	return 0
+}
+
+// Usage returns usage counters of each key, keyed by key name.
+func (r *Registry) Usage() (m map[string]Usage) {
+	m = map[string]Usage{}
+	if r == nil {
+		return m
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for name, u := range r.usage {
+		m[name] = u.Usage
+	}
+	return m
+}
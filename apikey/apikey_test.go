@@ -0,0 +1,93 @@
+package apikey
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	assert := assert.New(t)
+
+	var disabled *Registry
+	k, ok := disabled.Lookup("anything")
+	assert.Nil(k)
+	assert.True(ok)
+	assert.Zero(disabled.Admit(k, time.Now()))
+	assert.Empty(disabled.Usage())
+
+	filename := filepath.Join(t.TempDir(), "keys.json")
+	assert.NoError(os.WriteFile(filename, []byte(`{
+		"keys": [
+			{ "key": "s-app", "name": "app", "defaults": "network=yoursunny&k=3", "rate": 1, "burst": 2 },
+			{ "key": "s-quota", "quota": 2 },
+			{ "key": "s-free" }
+		]
+	}`), 0o644))
+	r := NewRegistry(filename)
+
+	k, ok = r.Lookup("")
+	assert.Nil(k)
+	assert.True(ok)
+	k, ok = r.Lookup("s-unknown")
+	assert.Nil(k)
+	assert.False(ok)
+
+	app, ok := r.Lookup("s-app")
+	if !assert.True(ok) {
+		return
+	}
+	assert.Equal("app", app.Name)
+	assert.Equal(url.Values{"k": {"1"}, "network": {"yoursunny"}, "cap": {"udp"}},
+		app.Apply(url.Values{"k": {"1"}, "cap": {"udp"}}))
+	assert.Equal(url.Values{"k": {"3"}, "network": {"yoursunny"}}, app.Apply(url.Values{}))
+
+	quota, _ := r.Lookup("s-quota")
+	assert.Equal("key1", quota.Name)
+	free, _ := r.Lookup("s-free")
+	var nilKey *Key
+	assert.Equal(url.Values{"k": {"1"}}, nilKey.Apply(url.Values{"k": {"1"}}))
+
+	t0 := time.Now()
+	assert.Zero(r.Admit(app, t0))
+	assert.Zero(r.Admit(app, t0))
+	assert.Equal(time.Second, r.Admit(app, t0))
+	assert.Zero(r.Admit(app, t0.Add(time.Second)))
+
+	assert.Zero(r.Admit(quota, t0))
+	assert.Zero(r.Admit(quota, t0))
+	assert.Equal(12*time.Hour, r.Admit(quota, t0))
+
+	for range 100 {
+		assert.Zero(r.Admit(free, t0))
+	}
+
+	usage := r.Usage()
+	assert.Equal(Usage{Requests: 3, Rejected: 1}, usage["app"])
+	assert.Equal(Usage{Requests: 2, Rejected: 1}, usage["key1"])
+	assert.Equal(Usage{Requests: 100}, usage["key2"])
+
+	// reload keeps counters of existing key names
+	assert.NoError(os.WriteFile(filename, []byte(`{ "keys": [ { "key": "s-app2", "name": "app", "rate": 10 } ] }`), 0o644))
+	os.Chtimes(filename, t0, t0.Add(time.Minute))
+	r.checked = time.Time{}
+	_, ok = r.Lookup("s-app")
+	assert.False(ok)
+	app, ok = r.Lookup("s-app2")
+	assert.True(ok)
+	assert.Equal(float64(10), app.Burst)
+	usage = r.Usage()
+	assert.Len(usage, 1)
+	assert.Equal(Usage{Requests: 3, Rejected: 1}, usage["app"])
+
+	// invalid file keeps previous keys
+	assert.NoError(os.WriteFile(filename, []byte(`{ "keys": [ { "name": "no-secret" } ] }`), 0o644))
+	os.Chtimes(filename, t0, t0.Add(2*time.Minute))
+	r.checked = time.Time{}
+	_, ok = r.Lookup("s-app2")
+	assert.True(ok)
+}
@@ -3,11 +3,13 @@ package availlist
 import (
 	"context"
 	"fmt"
+	"maps"
 	"math/rand"
 	"reflect"
 	"sync"
 	"time"
 
+	"github.com/11th-ndn-hackathon/ndn-fch/country"
 	"github.com/11th-ndn-hackathon/ndn-fch/health"
 	"github.com/11th-ndn-hackathon/ndn-fch/logging"
 	"github.com/11th-ndn-hackathon/ndn-fch/model"
@@ -17,6 +19,7 @@ import (
 
 var (
 	list        []model.RouterAvail
+	listIndex   *model.SpatialIndex
 	listUpdated time.Time
 	listLock    sync.RWMutex
 )
@@ -28,6 +31,13 @@ func List() (l []model.RouterAvail, updated time.Time) {
 	return list, listUpdated
 }
 
+// Index returns spatial index of available router list.
+func Index() *model.SpatialIndex {
+	listLock.RLock()
+	defer listLock.RUnlock()
+	return listIndex
+}
+
 var logger = logging.New("availlist")
 
 var (
@@ -37,9 +47,24 @@ var (
 )
 
 type availInfo struct {
-	tf model.TransportIPFamily
-	id string
-	ok bool
+	tf   model.TransportIPFamily
+	id   string
+	ok   bool
+	rtt  float64 // 0 if not measured
+	loss float64
+
+	samples []RTTSample
+}
+
+// smoothFactor is the EWMA gain applied to RTT and loss samples.
+const smoothFactor = 1.0 / 8
+
+func smooth(m map[model.TransportIPFamily]float64, tf model.TransportIPFamily, sample float64) {
+	if old, ok := m[tf]; ok {
+		m[tf] = old + (sample-old)*smoothFactor
+	} else {
+		m[tf] = sample
+	}
 }
 
 func refresh(ctx context.Context) {
@@ -61,12 +86,22 @@ func refresh(ctx context.Context) {
 		}
 	}
 
+	prefixOwner := make(map[string]string)
+	for _, router := range routers {
+		if p := router.Prefix(); p != "" {
+			prefixOwner[p] = router.ID()
+		}
+	}
+
 	availMap := make(map[string]*model.RouterAvail)
 	for _, router := range routers {
 		router := router
 		availMap[router.ID()] = &model.RouterAvail{
 			Router:    router,
 			Available: map[model.TransportIPFamily]bool{},
+			RTT:       map[model.TransportIPFamily]float64{},
+			Loss:      map[model.TransportIPFamily]float64{},
+			Country:   routerCountry(router),
 		}
 	}
 	for _, router := range oldAvail {
@@ -74,15 +109,24 @@ func refresh(ctx context.Context) {
 		if newRouter == nil {
 			continue
 		}
-		for tf, ok := range router.Available {
-			newRouter.Available[tf] = ok
-		}
+		maps.Copy(newRouter.Available, router.Available)
+		maps.Copy(newRouter.RTT, router.RTT)
+		maps.Copy(newRouter.Loss, router.Loss)
 	}
 
+	var samples []RTTSample
 	collect := make(chan availInfo)
+	collectDone := make(chan struct{})
 	go func() {
+		defer close(collectDone)
 		for ai := range collect {
-			availMap[ai.id].Available[ai.tf] = ai.ok
+			samples = append(samples, ai.samples...)
+			router := availMap[ai.id]
+			router.Available[ai.tf] = ai.ok
+			if ai.rtt > 0 {
+				smooth(router.RTT, ai.tf, ai.rtt)
+			}
+			smooth(router.Loss, ai.tf, ai.loss)
 		}
 	}()
 
@@ -103,13 +147,10 @@ func refresh(ctx context.Context) {
 					TransportIPFamily: tf,
 					Router:            connect,
 				}
-				for _, dest := range destinations {
+				dests := probeDestinations(destinations, router.Prefix())
+				for _, dest := range dests {
 					request.Names = append(request.Names, fmt.Sprintf("%s/ping/ndn-fch-2021/%d", dest, rand.Int()))
 				}
-				if n := len(request.Names); n > MaxNames {
-					rand.Shuffle(n, reflect.Swapper(request.Names))
-					request.Names = request.Names[:MaxNames]
-				}
 
 				logEntry := logger.With(
 					zap.String("transport", string(tf.Transport)),
@@ -129,23 +170,39 @@ func refresh(ctx context.Context) {
 						zap.Bool("connected", response.Connected),
 						zap.String("connect-error", response.ConnectError),
 					)
-					collect <- availInfo{id: router.ID(), tf: tf, ok: false}
+					collect <- availInfo{id: router.ID(), tf: tf, ok: false, loss: 1}
 					return
 				}
 
 				nSuccess, nFailure := response.Count()
 				verdict := nSuccess*2 > nFailure
+				rtt, _ := response.MinRTT()
 				logEntry.Debug("probe response",
 					zap.Int("success-count", nSuccess),
 					zap.Int("failure-count", nFailure),
+					zap.Float64("min-rtt", rtt),
 					zap.Bool("verdict", verdict),
 				)
-				collect <- availInfo{id: router.ID(), tf: tf, ok: verdict}
+				ai := availInfo{id: router.ID(), tf: tf, ok: verdict, rtt: rtt}
+				if n := nSuccess + nFailure; n > 0 {
+					ai.loss = float64(nFailure) / float64(n)
+				}
+				ai.samples = interRouterSamples(router, dests, response, prefixOwner)
+				collect <- ai
 			}(router, tf, connect)
 		}
 	}
 	wg.Wait()
 	close(collect)
+	<-collectDone
+
+	saveSamples(samples)
+	coords := updateNetCoords(samples, func(id string) bool { return availMap[id] != nil })
+	for id, router := range availMap {
+		if c, ok := coords[id]; ok {
+			router.Coord = &c
+		}
+	}
 
 	listLock.Lock()
 	defer listLock.Unlock()
@@ -153,6 +210,7 @@ func refresh(ctx context.Context) {
 	for _, router := range availMap {
 		list = append(list, *router)
 	}
+	listIndex = model.NewSpatialIndex(list)
 	listUpdated = time.Now().UTC()
 	logger.Info("updating", zap.Any("avail", list))
 }
@@ -177,3 +235,11 @@ func RefreshLoop(ctx context.Context) {
 		refreshOnce()
 	}
 }
+
+// routerCountry returns the country of a router, from its country tag or derived from its position.
+func routerCountry(router model.Router) string {
+	if c := country.Normalize(router.Tags()[model.TagCountry]); c != "" {
+		return c
+	}
+	return country.Locate(router.Position())
+}
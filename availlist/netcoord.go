@@ -0,0 +1,119 @@
+package availlist
+
+import (
+	"encoding/json"
+	"maps"
+	"math/rand"
+	"os"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/health"
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"go.uber.org/zap"
+)
+
+var (
+	// SamplesFile is a filename to append inter-router RTT samples in JSON lines format.
+	// These can be used with ndn-fch-vivaldi-eval command.
+	SamplesFile string
+
+	// NetCoordRounds is the number of passes over RTT samples in each refresh.
+	NetCoordRounds = 4
+
+	netCoords = map[string]model.NetCoord{}
+)
+
+// RTTSample is an inter-router RTT sample.
+type RTTSample struct {
+	Time int64   `json:"t"` // milliseconds since epoch
+	Src  string  `json:"src"`
+	Dst  string  `json:"dst"`
+	RTT  float64 `json:"rtt"` // milliseconds
+}
+
+// probeDestinations selects destination prefixes for probing a router.
+// The router's own prefix, if reachable, is placed first, so that its RTT can be subtracted
+// from other names to obtain inter-router RTT.
+func probeDestinations(destinations []string, self string) []string {
+	dests := slices.Clone(destinations)
+	rand.Shuffle(len(dests), reflect.Swapper(dests))
+	if i := slices.Index(dests, self); self != "" && i > 0 {
+		dests[0], dests[i] = dests[i], dests[0]
+	}
+	return dests[:min(len(dests), MaxNames)]
+}
+
+// interRouterSamples extracts inter-router RTT samples from a probe response.
+func interRouterSamples(router model.Router, dests []string, response health.ProbeResponse, prefixOwner map[string]string) (samples []RTTSample) {
+	if len(dests) == 0 || len(response.Probes) != len(dests) || dests[0] != router.Prefix() || !response.Probes[0].OK {
+		return nil
+	}
+
+	base, now := response.Probes[0].RTT, time.Now().UnixMilli()
+	for i, res := range response.Probes[1:] {
+		dst := prefixOwner[dests[i+1]]
+		if !res.OK || dst == "" || dst == router.ID() || res.RTT <= base {
+			continue
+		}
+		samples = append(samples, RTTSample{
+			Time: now,
+			Src:  router.ID(),
+			Dst:  dst,
+			RTT:  res.RTT - base,
+		})
+	}
+	return samples
+}
+
+// TrainNetCoords applies RTT samples to Vivaldi network coordinates in rounds passes.
+// Samples are shuffled in place before each pass; nodes absent from coords start at the origin.
+func TrainNetCoords(coords map[string]model.NetCoord, samples []RTTSample, rounds int) {
+	for range rounds {
+		rand.Shuffle(len(samples), reflect.Swapper(samples))
+		for _, sample := range samples {
+			src, ok := coords[sample.Src]
+			if !ok {
+				src = model.NewNetCoord()
+			}
+			dst, ok := coords[sample.Dst]
+			if !ok {
+				dst = model.NewNetCoord()
+			}
+			src.Update(dst, sample.RTT)
+			dst.Update(src, sample.RTT)
+			coords[sample.Src], coords[sample.Dst] = src, dst
+		}
+	}
+}
+
+// updateNetCoords applies RTT samples to Vivaldi network coordinates.
+// Coordinates of routers that are not current, as determined by isCurrent, are deleted.
+// Returns a copy of current coordinates.
+func updateNetCoords(samples []RTTSample, isCurrent func(id string) bool) map[string]model.NetCoord {
+	maps.DeleteFunc(netCoords, func(id string, _ model.NetCoord) bool { return !isCurrent(id) })
+	TrainNetCoords(netCoords, samples, NetCoordRounds)
+	return maps.Clone(netCoords)
+}
+
+func saveSamples(samples []RTTSample) {
+	if SamplesFile == "" || len(samples) == 0 {
+		return
+	}
+
+	f, e := os.OpenFile(SamplesFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if e != nil {
+		logger.Warn("save samples", zap.Error(e))
+		return
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, sample := range samples {
+		if e := enc.Encode(sample); e != nil {
+			logger.Warn("save samples", zap.Error(e))
+			return
+		}
+	}
+}
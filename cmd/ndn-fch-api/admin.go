@@ -0,0 +1,40 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/shadow"
+)
+
+var (
+	adminToken      string
+	shadowEvaluator *shadow.Evaluator
+)
+
+func init() {
+	http.HandleFunc("/admin/shadow", requireAdmin(handleShadow))
+}
+
+// requireAdmin restricts a handler to requests bearing the admin token.
+// If no admin token is configured, admin endpoints are disabled.
+func requireAdmin(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if adminToken == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+adminToken)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	}
+}
+
+func handleShadow(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", mimeJSON)
+	j, _ := json.Marshal(shadowEvaluator.Stats())
+	w.Write(j)
+}
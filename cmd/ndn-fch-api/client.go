@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/apikey"
+	"github.com/11th-ndn-hackathon/ndn-fch/country"
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/11th-ndn-hackathon/ndn-fch/policy"
+	"github.com/11th-ndn-hackathon/ndn-fch/routerlist"
+)
+
+var (
+	// clientIPHeader is a request header that carries client IP address from a trusted frontend.
+	// If empty, the TCP peer address is used.
+	clientIPHeader string
+
+	// trustedHops is the number of trusted proxies that append to clientIPHeader.
+	// Client IP address is the entry appended by the outermost trusted proxy, i.e. the trustedHops-th
+	// entry from the right; entries to its left are written by the client and cannot be trusted.
+	trustedHops = 1
+
+	// clientCountryHeader is a request header that carries client country code from a trusted frontend.
+	// If empty, client country is determined by IP geolocation.
+	clientCountryHeader string
+
+	// accessPolicy restricts routers to clients, nil if disabled.
+	accessPolicy *policy.File
+
+	// apiKeys is the API key registry, nil if disabled.
+	apiKeys *apikey.Registry
+
+	// stickyByIP enables sticky assignment keyed by client IP prefix.
+	stickyByIP bool
+)
+
+// clientIP determines client IP address of a request.
+func clientIP(r *http.Request) (ip netip.Addr) {
+	if clientIPHeader != "" {
+		var entries []string
+		for _, value := range r.Header.Values(clientIPHeader) {
+			entries = append(entries, strings.Split(value, ",")...)
+		}
+		if i := len(entries) - max(1, trustedHops); i >= 0 {
+			if ip, e := netip.ParseAddr(strings.TrimSpace(entries[i])); e == nil {
+				return ip.Unmap()
+			}
+		}
+	}
+
+	host, _, e := net.SplitHostPort(r.RemoteAddr)
+	if e != nil {
+		host = r.RemoteAddr
+	}
+	ip, _ = netip.ParseAddr(host)
+	return ip.Unmap()
+}
+
+// clientPrefix returns the /24 or /48 prefix containing a client IP address.
+func clientPrefix(ip netip.Addr) netip.Prefix {
+	bits := 48
+	if ip.Is4() {
+		bits = 24
+	}
+	prefix, _ := ip.Prefix(bits)
+	return prefix
+}
+
+// clientAccess evaluates access policy for a request.
+// Returns nil if no router is denied to the client.
+func clientAccess(r *http.Request, ip netip.Addr, key *apikey.Key) model.AccessPolicy {
+	p := accessPolicy.Get()
+	if p == nil {
+		return nil
+	}
+	client := policy.Client{IP: ip, Country: clientCountry(r, ip)}
+	if key != nil {
+		client.Key = key.Name
+	}
+	return p.For(client)
+}
+
+// clientAPIKey returns the API key of a request, empty if none.
+func clientAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	return r.URL.Query().Get("apikey")
+}
+
+// clientCountry determines client country of a request.
+// Query parameters are not trusted, because they are controlled by the client.
+func clientCountry(r *http.Request, ip netip.Addr) string {
+	if clientCountryHeader != "" {
+		if c := country.Normalize(r.Header.Get(clientCountryHeader)); c != "" {
+			return c
+		}
+	}
+	if routerlist.Geolocation != nil && ip.IsValid() {
+		if pos, ok := routerlist.Geolocation.Locate(ip); ok {
+			return country.Locate(pos)
+		}
+	}
+	return ""
+}
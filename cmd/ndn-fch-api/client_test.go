@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/11th-ndn-hackathon/ndn-fch/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	assert := assert.New(t)
+	defer func(header string, hops int) { clientIPHeader, trustedHops = header, hops }(clientIPHeader, trustedHops)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "198.51.100.9:40000"
+	r.Header.Add("X-Forwarded-For", "192.0.2.1, 203.0.113.5")
+	r.Header.Add("X-Forwarded-For", "203.0.113.7")
+
+	clientIPHeader = ""
+	assert.Equal(netip.MustParseAddr("198.51.100.9"), clientIP(r))
+
+	// leftmost entries are written by the client and must be ignored
+	clientIPHeader, trustedHops = "X-Forwarded-For", 1
+	assert.Equal(netip.MustParseAddr("203.0.113.7"), clientIP(r))
+
+	trustedHops = 2
+	assert.Equal(netip.MustParseAddr("203.0.113.5"), clientIP(r))
+
+	trustedHops = 4
+	assert.Equal(netip.MustParseAddr("198.51.100.9"), clientIP(r))
+
+	r.Header.Set("X-Forwarded-For", "::ffff:192.0.2.2")
+	trustedHops = 1
+	assert.Equal(netip.MustParseAddr("192.0.2.2"), clientIP(r))
+}
+
+type partnerRouter struct{ id string }
+
+func (r partnerRouter) ID() string                                   { return r.id }
+func (r partnerRouter) Position() model.LonLat                       { return model.LonLat{} }
+func (r partnerRouter) Prefix() string                               { return "/" + r.id }
+func (r partnerRouter) ConnectString(model.TransportIPFamily) string { return r.id + ":6363" }
+func (r partnerRouter) Neighbors() map[string]int                    { return nil }
+func (r partnerRouter) Tags() map[string]string                      { return nil }
+
+func TestClientAccess(t *testing.T) {
+	assert := assert.New(t)
+	defer func(header string, hops int, p *policy.File) {
+		clientIPHeader, trustedHops, accessPolicy = header, hops, p
+	}(clientIPHeader, trustedHops, accessPolicy)
+
+	filename := filepath.Join(t.TempDir(), "policy.json")
+	assert.NoError(os.WriteFile(filename, []byte(`{
+		"rules": [ { "name": "partner", "routers": { "ids": ["P"] }, "clients": { "cidrs": ["192.0.2.0/24"] } } ]
+	}`), 0o644))
+	var e error
+	accessPolicy, e = policy.NewFile(filename)
+	assert.NoError(e)
+	clientIPHeader, trustedHops = "X-Forwarded-For", 1
+
+	r := httptest.NewRequest("GET", "/routers.json", nil)
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	access := clientAccess(r, clientIP(r), nil)
+	if assert.NotNil(access) {
+		assert.Equal("partner", access.Deny(partnerRouter{"P"}))
+		assert.Equal("", access.Deny(partnerRouter{"Q"}))
+	}
+
+	// a partner address written by the client before the frontend entry is not trusted
+	r.Header.Set("X-Forwarded-For", "192.0.2.1, 198.51.100.1")
+	access = clientAccess(r, clientIP(r), nil)
+	if assert.NotNil(access) {
+		assert.Equal("partner", access.Deny(partnerRouter{"P"}))
+	}
+
+	r.Header.Set("X-Forwarded-For", "198.51.100.1, 192.0.2.1")
+	assert.Nil(clientAccess(r, clientIP(r), nil))
+}
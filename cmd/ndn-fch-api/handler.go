@@ -4,9 +4,12 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"net/http"
+	"slices"
 	"time"
 
+	"github.com/11th-ndn-hackathon/ndn-fch/apikey"
 	"github.com/11th-ndn-hackathon/ndn-fch/availlist"
+	"github.com/11th-ndn-hackathon/ndn-fch/country"
 	"github.com/11th-ndn-hackathon/ndn-fch/model"
 	"github.com/elnormous/contenttype"
 )
@@ -16,15 +19,20 @@ func init() {
 		w.Write([]byte("User-Agent: *\nDisallow: /\n"))
 	})
 
-	http.HandleFunc("/routers.json", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/routers.json", limitClient(func(w http.ResponseWriter, r *http.Request, key *apikey.Key) {
 		list, updated := availlist.List()
+		if access := clientAccess(r, clientIP(r), key); access != nil {
+			list = slices.DeleteFunc(slices.Clone(list), func(router model.RouterAvail) bool {
+				return access.Deny(router.Router) != ""
+			})
+		}
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("Last-Modified", updated.Format(http.TimeFormat))
 		j, _ := json.Marshal(list)
 		w.Write(j)
-	})
+	}))
 
-	http.HandleFunc("/", handleQuery)
+	http.HandleFunc("/", limitClient(handleQuery))
 }
 
 const (
@@ -39,7 +47,20 @@ var (
 	}
 )
 
-func handleQuery(w http.ResponseWriter, r *http.Request) {
+func handleQuery(w http.ResponseWriter, r *http.Request, key *apikey.Key) {
+	query := key.Apply(r.URL.Query())
+	if len(query["cap"]) > maxCaps {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if c := query.Get("country"); c != "" {
+		if c = country.Normalize(c); c == "" {
+			http.Error(w, "invalid country", http.StatusBadRequest)
+			return
+		}
+		query.Set("country", c)
+	}
+
 	avail, updated := availlist.List()
 	if len(avail) == 0 {
 		w.Header().Set("Retry-After", "60")
@@ -47,7 +68,21 @@ func handleQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	queries := model.ParseQueries(r.URL.RawQuery)
+	queries := model.ParseQueries(query.Encode())
+	index := availlist.Index()
+	ip := clientIP(r)
+	access := clientAccess(r, ip, key)
+	for i := range queries {
+		queries[i].Index = index
+		queries[i].Policy = access
+		if stickyByIP && ip.IsValid() && queries[i].Client == "" {
+			queries[i].Client = clientPrefix(ip).String()
+		}
+		if queries[i].Country == "" {
+			queries[i].Country = country.Locate(queries[i].Position)
+		}
+		queries[i].Load = loadTracker
+	}
 	response := model.QueryResponse{
 		Updated: updated.UnixNano() / int64(time.Millisecond),
 		Routers: []model.QueryResponseRouter{},
@@ -59,25 +94,48 @@ func handleQuery(w http.ResponseWriter, r *http.Request) {
 	}
 
 	preferLegacySyntax := contentType != mimeJSON
+	var routerIDs []string
 	for _, q := range queries {
-		for _, r := range q.Execute(avail) {
-			connect := r.ConnectString(model.TransportIPFamily{Transport: q.Transport, Family: 4})
+		res, executed := queryCache.Execute(q, avail, updated)
+		shadowEvaluator.Submit(executed, res.Routers, avail)
+		if res.Shortfall != nil {
+			response.Shortfall = append(response.Shortfall, *res.Shortfall)
+		}
+		if q.Explain {
+			explanation := model.QueryExplanation{
+				Transport:  q.Transport,
+				Candidates: res.Explain,
+			}
+			if len(q.Transports) > 1 {
+				explanation.Transports = q.Transports
+			}
+			response.Explain = append(response.Explain, explanation)
+		}
+		for _, r := range res.Routers {
+			connect := r.ConnectString(model.TransportIPFamily{Transport: r.Transport, Family: 4})
 			if connect == "" {
-				connect = r.ConnectString(model.TransportIPFamily{Transport: q.Transport, Family: 6})
+				connect = r.ConnectString(model.TransportIPFamily{Transport: r.Transport, Family: 6})
 			}
 			if connect == "" {
 				continue
 			}
 			if preferLegacySyntax {
-				connect = model.MakeLegacyConnectString(q.Transport, connect)
+				connect = model.MakeLegacyConnectString(r.Transport, connect)
 			}
 			response.Routers = append(response.Routers, model.QueryResponseRouter{
-				Transport: q.Transport,
+				Transport: r.Transport,
 				Connect:   connect,
 				Prefix:    r.Prefix(),
+				Tags:      r.Tags(),
+
+				Score:           r.Score,
+				ScoreComponents: r.Components,
 			})
+			routerIDs = append(routerIDs, r.ID())
 		}
 	}
+	slices.Sort(routerIDs)
+	loadTracker.Record(slices.Compact(routerIDs)...)
 
 	w.Header().Set("Content-Type", contentType)
 	w.Header().Set("Last-Modified", updated.Format(http.TimeFormat))
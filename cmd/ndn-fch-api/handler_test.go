@@ -0,0 +1,23 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleQueryInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	serve := func(target string) int {
+		w := httptest.NewRecorder()
+		handleQuery(w, httptest.NewRequest("GET", target, nil), nil)
+		return w.Code
+	}
+
+	assert.Equal(http.StatusBadRequest, serve("/?country=ZZ"))
+	assert.Equal(http.StatusBadRequest, serve("/?country=Atlantis"))
+	assert.Equal(http.StatusBadRequest, serve("/?cap=udp&cap=udp&cap=udp&cap=udp&cap=udp&cap=udp&cap=udp&cap=udp&cap=udp"))
+}
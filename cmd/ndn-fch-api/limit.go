@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/apikey"
+	"github.com/11th-ndn-hackathon/ndn-fch/ratelimit"
+)
+
+var (
+	// maxQueryLength limits query string length, in octets.
+	maxQueryLength = 2048
+	// maxCaps limits the number of cap= query parameters.
+	maxCaps = 8
+
+	ipRate, ipBurst         = 10.0, 50.0
+	prefixRate, prefixBurst = 50.0, 200.0
+	limiterEntries          = 100000
+
+	// ipLimiter limits requests per client IP address.
+	ipLimiter *ratelimit.Limiter[netip.Addr]
+	// prefixLimiter limits requests per client IP prefix, as determined by clientPrefix.
+	prefixLimiter *ratelimit.Limiter[netip.Prefix]
+)
+
+// tooManyRequests responds with 429 Too Many Requests.
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.FormatInt(int64(max(1, math.Ceil(retryAfter.Seconds()))), 10))
+	w.WriteHeader(http.StatusTooManyRequests)
+}
+
+// keyedHandler is an HTTP handler that receives the API key of a request, nil if none.
+type keyedHandler func(w http.ResponseWriter, r *http.Request, key *apikey.Key)
+
+// limitClient protects a handler from abusive clients.
+// It rejects overly long query strings and unknown API keys.
+// A request with a registered API key is admitted by the API key registry;
+// other requests are subject to per-IP and per-prefix rate limits.
+func limitClient(h keyedHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(r.URL.RawQuery) > maxQueryLength {
+			w.WriteHeader(http.StatusRequestURITooLong)
+			return
+		}
+
+		key, ok := apiKeys.Lookup(clientAPIKey(r))
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		now := time.Now()
+		if key != nil {
+			if retryAfter := apiKeys.Admit(key, now); retryAfter > 0 {
+				tooManyRequests(w, retryAfter)
+				return
+			}
+		} else if retryAfter := takeIP(r, now); retryAfter > 0 {
+			tooManyRequests(w, retryAfter)
+			return
+		}
+
+		h(w, r, key)
+	}
+}
+
+// limitIP applies per-IP and per-prefix rate limits to a handler, regardless of API key.
+func limitIP(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if retryAfter := takeIP(r, time.Now()); retryAfter > 0 {
+			tooManyRequests(w, retryAfter)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// takeIP consumes a token from the per-IP and per-prefix limiters.
+// Returns positive duration if the request should be rejected.
+func takeIP(r *http.Request, now time.Time) time.Duration {
+	ip := clientIP(r)
+	if !ip.IsValid() {
+		return 0
+	}
+	if retryAfter := ipLimiter.Take(ip, now); retryAfter > 0 {
+		return retryAfter
+	}
+	return prefixLimiter.Take(clientPrefix(ip), now)
+}
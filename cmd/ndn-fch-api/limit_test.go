@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/apikey"
+	"github.com/11th-ndn-hackathon/ndn-fch/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitClient(t *testing.T) {
+	assert := assert.New(t)
+	defer func(keys *apikey.Registry, ipL *ratelimit.Limiter[netip.Addr], prefixL *ratelimit.Limiter[netip.Prefix]) {
+		apiKeys, ipLimiter, prefixLimiter = keys, ipL, prefixL
+	}(apiKeys, ipLimiter, prefixLimiter)
+
+	filename := filepath.Join(t.TempDir(), "keys.json")
+	assert.NoError(os.WriteFile(filename, []byte(`{ "keys": [ { "key": "s-app", "name": "app", "rate": 1, "burst": 1 } ] }`), 0o644))
+	apiKeys = apikey.NewRegistry(filename)
+	ipLimiter = ratelimit.NewLimiter[netip.Addr](1, 2, 10)
+	prefixLimiter = nil
+
+	var keyName string
+	h := limitClient(func(w http.ResponseWriter, r *http.Request, key *apikey.Key) {
+		keyName = ""
+		if key != nil {
+			keyName = key.Name
+		}
+	})
+	serve := func(target string) int {
+		r := httptest.NewRequest("GET", target, nil)
+		r.RemoteAddr = "192.0.2.1:40000"
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w.Code
+	}
+
+	assert.Equal(http.StatusUnauthorized, serve("/routers.json?apikey=s-unknown"))
+
+	// a registered key is limited by the key registry, not the IP limiter
+	assert.Equal(http.StatusOK, serve("/routers.json?apikey=s-app"))
+	assert.Equal("app", keyName)
+	assert.Equal(http.StatusTooManyRequests, serve("/routers.json?apikey=s-app"))
+	assert.Equal(http.StatusTooManyRequests, serve("/?apikey=s-app"))
+	assert.Equal(ratelimit.LimiterStats{}, ipLimiter.Stats())
+
+	assert.Equal(http.StatusOK, serve("/"))
+	assert.Equal("", keyName)
+	assert.Equal(http.StatusOK, serve("/routers.json"))
+	assert.Equal(http.StatusTooManyRequests, serve("/"))
+}
+
+func TestLimitIP(t *testing.T) {
+	assert := assert.New(t)
+	defer func(ipL *ratelimit.Limiter[netip.Addr], prefixL *ratelimit.Limiter[netip.Prefix]) {
+		ipLimiter, prefixLimiter = ipL, prefixL
+	}(ipLimiter, prefixLimiter)
+	ipLimiter = ratelimit.NewLimiter[netip.Addr](1, 1, 10)
+	prefixLimiter = nil
+
+	h := limitIP(func(w http.ResponseWriter, r *http.Request) {})
+	serve := func(remote string) int {
+		r := httptest.NewRequest("POST", "/register", nil)
+		r.RemoteAddr = remote
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w.Code
+	}
+
+	assert.Equal(http.StatusOK, serve("192.0.2.1:40000"))
+	assert.Equal(http.StatusTooManyRequests, serve("192.0.2.1:40001"))
+	assert.Equal(http.StatusOK, serve("192.0.2.2:40000"))
+}
@@ -2,12 +2,22 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
+	"net/netip"
 	"os"
+	"strings"
 
+	"github.com/11th-ndn-hackathon/ndn-fch/apikey"
 	"github.com/11th-ndn-hackathon/ndn-fch/availlist"
 	"github.com/11th-ndn-hackathon/ndn-fch/health"
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/11th-ndn-hackathon/ndn-fch/policy"
+	"github.com/11th-ndn-hackathon/ndn-fch/querycache"
+	"github.com/11th-ndn-hackathon/ndn-fch/ratelimit"
 	"github.com/11th-ndn-hackathon/ndn-fch/routerlist"
+	"github.com/11th-ndn-hackathon/ndn-fch/routerload"
+	"github.com/11th-ndn-hackathon/ndn-fch/shadow"
 	"github.com/urfave/cli/v2"
 )
 
@@ -31,6 +41,152 @@ var app = &cli.App{
 			Destination: &availlist.MaxNames,
 			Value:       availlist.MaxNames,
 		},
+		&cli.StringFlag{
+			Name:  "rank",
+			Usage: "default ranker",
+			Value: string(model.DefaultRank),
+		},
+		&cli.StringFlag{
+			Name:        "rtt-samples",
+			Usage:       "append inter-router RTT samples to file",
+			Destination: &availlist.SamplesFile,
+		},
+		&cli.Float64Flag{
+			Name:        "rank-weight-distance",
+			Usage:       "RTT ranking weight of geographical distance, per 100 km",
+			Destination: &model.DefaultRankWeights.Distance,
+			Value:       model.DefaultRankWeights.Distance,
+		},
+		&cli.Float64Flag{
+			Name:        "rank-weight-rtt",
+			Usage:       "RTT ranking weight of measured RTT, per millisecond",
+			Destination: &model.DefaultRankWeights.RTT,
+			Value:       model.DefaultRankWeights.RTT,
+		},
+		&cli.Float64Flag{
+			Name:        "rank-weight-loss",
+			Usage:       "RTT ranking weight of measured loss rate",
+			Destination: &model.DefaultRankWeights.Loss,
+			Value:       model.DefaultRankWeights.Loss,
+		},
+		&cli.StringSliceFlag{
+			Name:  "network-alias",
+			Usage: "network alias usable in network= query parameter, written as name=/prefix1,/prefix2",
+		},
+		&cli.StringSliceFlag{
+			Name:  "region-alias",
+			Usage: "region alias usable in region= query parameter, written as name=CC,CC",
+		},
+		&cli.StringFlag{
+			Name:        "client-ip-header",
+			Usage:       "request header containing client IP address, set by a trusted frontend",
+			Destination: &clientIPHeader,
+		},
+		&cli.IntFlag{
+			Name:        "trusted-hops",
+			Usage:       "number of trusted proxies appending to --client-ip-header; client IP is this many entries from the right",
+			Destination: &trustedHops,
+			Value:       trustedHops,
+		},
+		&cli.StringFlag{
+			Name:        "client-country-header",
+			Usage:       "request header containing client country code, set by a trusted frontend",
+			Destination: &clientCountryHeader,
+		},
+		&cli.IntFlag{
+			Name:        "max-k",
+			Usage:       "maximum number of routers per transport in a query; 0 means unlimited",
+			Destination: &model.MaxCount,
+			Value:       model.MaxCount,
+		},
+		&cli.IntFlag{
+			Name:        "max-query-length",
+			Usage:       "maximum query string length in octets",
+			Destination: &maxQueryLength,
+			Value:       maxQueryLength,
+		},
+		&cli.IntFlag{
+			Name:        "max-caps",
+			Usage:       "maximum number of cap= query parameters",
+			Destination: &maxCaps,
+			Value:       maxCaps,
+		},
+		&cli.Float64Flag{
+			Name:        "ip-rate",
+			Usage:       "per-IP rate limit in requests per second; 0 disables",
+			Destination: &ipRate,
+			Value:       ipRate,
+		},
+		&cli.Float64Flag{
+			Name:        "ip-burst",
+			Usage:       "per-IP burst size in requests",
+			Destination: &ipBurst,
+			Value:       ipBurst,
+		},
+		&cli.Float64Flag{
+			Name:        "prefix-rate",
+			Usage:       "per-prefix (/24 or /48) rate limit in requests per second; 0 disables",
+			Destination: &prefixRate,
+			Value:       prefixRate,
+		},
+		&cli.Float64Flag{
+			Name:        "prefix-burst",
+			Usage:       "per-prefix (/24 or /48) burst size in requests",
+			Destination: &prefixBurst,
+			Value:       prefixBurst,
+		},
+		&cli.IntFlag{
+			Name:        "limiter-entries",
+			Usage:       "maximum number of IP addresses and prefixes tracked by each rate limiter",
+			Destination: &limiterEntries,
+			Value:       limiterEntries,
+		},
+		&cli.StringFlag{
+			Name:  "api-keys",
+			Usage: "API key registry file, reloaded when changed",
+		},
+		&cli.StringFlag{
+			Name:  "policy",
+			Usage: "access policy file restricting routers to clients, reloaded when changed",
+		},
+		&cli.BoolFlag{
+			Name:        "sticky",
+			Usage:       "assign routers consistently by client IP prefix",
+			Destination: &stickyByIP,
+		},
+		&cli.Float64Flag{
+			Name:        "sticky-tolerance",
+			Usage:       "relative score band within which a client keeps its assigned router",
+			Destination: &model.StickyTolerance,
+			Value:       model.StickyTolerance,
+		},
+		&cli.DurationFlag{
+			Name:        "load-window",
+			Usage:       "sliding window for router load tracking",
+			Destination: &loadWindow,
+			Value:       loadWindow,
+		},
+		&cli.IntFlag{
+			Name:        "cache-size",
+			Usage:       "maximum query cache entries, 0 disables query cache",
+			Destination: &cacheSize,
+			Value:       cacheSize,
+		},
+		&cli.IntFlag{
+			Name:        "cache-precision",
+			Usage:       "geohash length of query cache cells",
+			Destination: &cachePrecision,
+			Value:       cachePrecision,
+		},
+		&cli.StringSliceFlag{
+			Name:  "shadow-rankers",
+			Usage: "alternative rankers evaluated alongside each query",
+		},
+		&cli.StringFlag{
+			Name:        "admin-token",
+			Usage:       "bearer token for /admin and /metrics endpoints; these endpoints are disabled if empty",
+			Destination: &adminToken,
+		},
 		&cli.StringFlag{
 			Name:     "probe",
 			Usage:    "UDP/WebSockets health probe URI",
@@ -46,6 +202,48 @@ var app = &cli.App{
 		if availlist.ProbeService, e = health.NewHTTPDispatcher(c.String("probe"), c.String("probe3")); e != nil {
 			return cli.Exit(e, 1)
 		}
+		if rank := model.RankMode(c.String("rank")); model.GetRanker(rank) != nil {
+			model.DefaultRank = rank
+		} else {
+			return cli.Exit(fmt.Sprintf("unknown ranker %s, available: %v", rank, model.RankerNames()), 1)
+		}
+		for _, alias := range c.StringSlice("network-alias") {
+			name, prefixes, ok := strings.Cut(alias, "=")
+			if !ok || name == "" || prefixes == "" {
+				return cli.Exit(fmt.Sprintf("bad network alias %s", alias), 1)
+			}
+			model.NetworkAliases[name] = strings.Split(prefixes, ",")
+		}
+		for _, alias := range c.StringSlice("region-alias") {
+			name, codes, ok := strings.Cut(alias, "=")
+			if !ok || len(name) <= 2 || codes == "" {
+				return cli.Exit(fmt.Sprintf("bad region alias %s, name must be longer than two letters", alias), 1)
+			}
+			model.Regions[name] = strings.Split(strings.ToUpper(codes), ",")
+		}
+		if loadWindow <= 0 {
+			return cli.Exit(fmt.Sprintf("bad load window %s, must be positive", loadWindow), 1)
+		}
+		apiKeys = apikey.NewRegistry(c.String("api-keys"))
+		ipLimiter = ratelimit.NewLimiter[netip.Addr](ipRate, ipBurst, limiterEntries)
+		prefixLimiter = ratelimit.NewLimiter[netip.Prefix](prefixRate, prefixBurst, limiterEntries)
+		if accessPolicy, e = policy.NewFile(c.String("policy")); e != nil {
+			return cli.Exit(fmt.Sprintf("cannot load policy: %v", e), 1)
+		}
+		loadTracker = routerload.NewTracker(loadWindow, 10)
+		if cacheSize > 0 {
+			queryCache = querycache.New(cachePrecision, cacheSize)
+		}
+
+		var shadowRankers []model.RankMode
+		for _, name := range c.StringSlice("shadow-rankers") {
+			rank := model.RankMode(name)
+			if model.GetRanker(rank) == nil {
+				return cli.Exit(fmt.Sprintf("unknown shadow ranker %s, available: %v", rank, model.RankerNames()), 1)
+			}
+			shadowRankers = append(shadowRankers, rank)
+		}
+		shadowEvaluator = shadow.NewEvaluator(shadowRankers, 4)
 		return nil
 	},
 	Action: func(c *cli.Context) (e error) {
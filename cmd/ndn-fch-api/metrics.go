@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/availlist"
+	"github.com/11th-ndn-hackathon/ndn-fch/querycache"
+	"github.com/11th-ndn-hackathon/ndn-fch/ratelimit"
+	"github.com/11th-ndn-hackathon/ndn-fch/routerload"
+)
+
+var (
+	loadWindow  = 10 * time.Minute
+	loadTracker *routerload.Tracker
+
+	cacheSize      = 10000
+	cachePrecision = 5
+	queryCache     *querycache.Cache
+)
+
+func init() {
+	http.HandleFunc("/metrics", requireAdmin(handleMetrics))
+}
+
+// writeMetric writes one sample in Prometheus text exposition format.
+func writeMetric(w io.Writer, name string, labels map[string]string, value float64) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		keys := make([]string, 0, len(labels))
+		for k := range labels {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, "%s=%q", k, labels[k])
+		}
+		b.WriteByte('}')
+	}
+	fmt.Fprintf(w, "%s %g\n", b.String(), value)
+}
+
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	avail, _ := availlist.List()
+	counts, total := loadTracker.Counts()
+	writeMetric(w, "ndn_fch_load_window_seconds", nil, loadWindow.Seconds())
+	writeMetric(w, "ndn_fch_load_responses", nil, float64(total))
+	for _, router := range avail {
+		labels := map[string]string{"router": router.ID()}
+		writeMetric(w, "ndn_fch_router_load_responses", labels, float64(counts[router.ID()]))
+		saturated := 0.0
+		if loadTracker.Saturated(router.Router) {
+			saturated = 1
+		}
+		writeMetric(w, "ndn_fch_router_saturated", labels, saturated)
+	}
+
+	cs := queryCache.Stats()
+	writeMetric(w, "ndn_fch_query_cache_requests", map[string]string{"result": "hit"}, float64(cs.Hits))
+	writeMetric(w, "ndn_fch_query_cache_requests", map[string]string{"result": "miss"}, float64(cs.Misses))
+	writeMetric(w, "ndn_fch_query_cache_requests", map[string]string{"result": "bypass"}, float64(cs.Bypass))
+	writeMetric(w, "ndn_fch_query_cache_entries", nil, float64(cs.Entries))
+
+	for _, l := range []struct {
+		scope string
+		stats ratelimit.LimiterStats
+	}{{"ip", ipLimiter.Stats()}, {"prefix", prefixLimiter.Stats()}} {
+		scope, ls := l.scope, l.stats
+		writeMetric(w, "ndn_fch_ratelimit_requests", map[string]string{"scope": scope, "result": "admitted"}, float64(ls.Admitted))
+		writeMetric(w, "ndn_fch_ratelimit_requests", map[string]string{"scope": scope, "result": "rejected"}, float64(ls.Rejected))
+		writeMetric(w, "ndn_fch_ratelimit_entries", map[string]string{"scope": scope}, float64(ls.Entries))
+	}
+
+	for name, u := range apiKeys.Usage() {
+		writeMetric(w, "ndn_fch_apikey_requests", map[string]string{"key": name, "result": "admitted"}, float64(u.Requests))
+		writeMetric(w, "ndn_fch_apikey_requests", map[string]string{"key": name, "result": "rejected"}, float64(u.Rejected))
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/routerlist"
+)
+
+const maxRegisterBody = 16 << 10
+
+func init() {
+	http.HandleFunc("POST /register", limitIP(handleRegister))
+}
+
+func handleRegister(w http.ResponseWriter, r *http.Request) {
+	body, e := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRegisterBody))
+	if e != nil {
+		http.Error(w, e.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	expires, e := routerlist.Register(body, r.Header.Get("Authorization"))
+	switch {
+	case errors.Is(e, routerlist.ErrUnauthorized):
+		http.Error(w, e.Error(), http.StatusUnauthorized)
+		return
+	case e != nil:
+		http.Error(w, e.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", mimeJSON)
+	j, _ := json.Marshal(struct {
+		Expires int64 `json:"expires"` // milliseconds since epoch
+	}{
+		Expires: expires.UnixNano() / int64(time.Millisecond),
+	})
+	w.Write(j)
+}
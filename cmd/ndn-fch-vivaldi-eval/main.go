@@ -0,0 +1,148 @@
+// Command ndn-fch-vivaldi-eval compares Vivaldi predicted RTT with observed inter-router RTT.
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/availlist"
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/urfave/cli/v2"
+)
+
+var app = &cli.App{
+	Name:  "ndn-fch-vivaldi-eval",
+	Usage: "evaluate Vivaldi network coordinates against observed RTT samples",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "samples",
+			Usage:    "RTT samples file written by ndn-fch-api --rtt-samples",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "routers",
+			Usage: "routers.json file for geographical distance baseline",
+		},
+		&cli.Float64Flag{
+			Name:  "train",
+			Usage: "fraction of samples, in time order, used for training",
+			Value: 0.8,
+		},
+		&cli.IntFlag{
+			Name:  "rounds",
+			Usage: "passes over training samples",
+			Value: 20,
+		},
+	},
+	Action: func(c *cli.Context) (e error) {
+		samples, e := readSamples(c.String("samples"))
+		if e != nil {
+			return cli.Exit(e, 1)
+		}
+		slices.SortStableFunc(samples, func(a, b availlist.RTTSample) int { return cmp.Compare(a.Time, b.Time) })
+		nTrain := int(float64(len(samples)) * min(1, max(0, c.Float64("train"))))
+		train, test := samples[:nTrain], samples[nTrain:]
+		if len(test) == 0 {
+			return cli.Exit("no test samples", 1)
+		}
+
+		coords := map[string]model.NetCoord{}
+		availlist.TrainNetCoords(coords, train, c.Int("rounds"))
+
+		fmt.Printf("samples: %d train, %d test, %d nodes\n", len(train), len(test), len(coords))
+		report("vivaldi", test, func(sample availlist.RTTSample) (float64, bool) {
+			src, ok0 := coords[sample.Src]
+			dst, ok1 := coords[sample.Dst]
+			return model.PredictRTT(src, dst), ok0 && ok1
+		})
+
+		if filename := c.String("routers"); filename != "" {
+			positions, e := readPositions(filename)
+			if e != nil {
+				return cli.Exit(e, 1)
+			}
+			report("geo", test, func(sample availlist.RTTSample) (float64, bool) {
+				src, ok0 := positions[sample.Src]
+				dst, ok1 := positions[sample.Dst]
+				return model.Distance(src, dst) / 100, ok0 && ok1
+			})
+		}
+		return nil
+	},
+}
+
+func readSamples(filename string) (samples []availlist.RTTSample, e error) {
+	f, e := os.Open(filename)
+	if e != nil {
+		return nil, e
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var sample availlist.RTTSample
+		if e := json.Unmarshal(scanner.Bytes(), &sample); e != nil {
+			return nil, e
+		}
+		samples = append(samples, sample)
+	}
+	return samples, scanner.Err()
+}
+
+func readPositions(filename string) (positions map[string]model.LonLat, e error) {
+	body, e := os.ReadFile(filename)
+	if e != nil {
+		return nil, e
+	}
+
+	var routers []struct {
+		ID       string       `json:"id"`
+		Position model.LonLat `json:"position"`
+	}
+	if e := json.Unmarshal(body, &routers); e != nil {
+		return nil, e
+	}
+
+	positions = map[string]model.LonLat{}
+	for _, r := range routers {
+		positions[r.ID] = r.Position
+	}
+	return positions, nil
+}
+
+func report(name string, test []availlist.RTTSample, predict func(sample availlist.RTTSample) (float64, bool)) {
+	var absErrors, relErrors []float64
+	for _, sample := range test {
+		predicted, ok := predict(sample)
+		if !ok {
+			continue
+		}
+		absErr := math.Abs(predicted - sample.RTT)
+		absErrors = append(absErrors, absErr)
+		relErrors = append(relErrors, absErr/sample.RTT)
+	}
+	if len(absErrors) == 0 {
+		fmt.Printf("%s: no predictions\n", name)
+		return
+	}
+
+	slices.Sort(absErrors)
+	slices.Sort(relErrors)
+	percentile := func(sorted []float64, p float64) float64 {
+		return sorted[int(p*float64(len(sorted)-1))]
+	}
+	fmt.Printf("%s: n=%d abs-error median=%.1fms p90=%.1fms rel-error median=%.3f p90=%.3f\n",
+		name, len(absErrors),
+		percentile(absErrors, 0.5), percentile(absErrors, 0.9),
+		percentile(relErrors, 0.5), percentile(relErrors, 0.9),
+	)
+}
+
+func main() {
+	app.Run(os.Args)
+}
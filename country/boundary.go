@@ -0,0 +1,157 @@
+package country
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+)
+
+//go:generate go run -C gen . -o ../boundary.bin
+
+//go:embed boundary.bin
+var boundaryBin []byte
+
+// scale converts degrees to stored integer units.
+const scale = 1e4
+
+// borderGap is the distance in degrees probed around a position that falls between polygons.
+// Adjacent polygons are simplified separately, so there may be small gaps along borders.
+const borderGap = 0.005
+
+type ring [][2]int32
+
+// contains determines whether a ring contains a point, using ray casting.
+func (r ring) contains(x, y int32) (inside bool) {
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		xi, yi, xj, yj := float64(r[i][0]), float64(r[i][1]), float64(r[j][0]), float64(r[j][1])
+		if (yi > float64(y)) != (yj > float64(y)) && float64(x) < (xj-xi)*(float64(y)-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+type polygon struct {
+	country string
+	min     [2]int32
+	max     [2]int32
+	rings   []ring // exterior ring followed by holes
+}
+
+func (p *polygon) contains(x, y int32) bool {
+	if x < p.min[0] || x > p.max[0] || y < p.min[1] || y > p.max[1] || !p.rings[0].contains(x, y) {
+		return false
+	}
+	for _, hole := range p.rings[1:] {
+		if hole.contains(x, y) {
+			return false
+		}
+	}
+	return true
+}
+
+// boundaries is a set of country polygons with a 1-degree grid index.
+type boundaries struct {
+	polygons []polygon
+	grid     map[[2]int16][]int
+}
+
+func gridCell(x, y int32) [2]int16 {
+	return [2]int16{int16(math.Floor(float64(x) / scale)), int16(math.Floor(float64(y) / scale))}
+}
+
+func (b *boundaries) locate(x, y int32) string {
+	for _, i := range b.grid[gridCell(x, y)] {
+		if p := &b.polygons[i]; p.contains(x, y) {
+			return p.country
+		}
+	}
+	return ""
+}
+
+// Locate returns the country containing a position.
+func (b *boundaries) Locate(pos model.LonLat) string {
+	x, y := int32(math.Round(pos[0]*scale)), int32(math.Round(pos[1]*scale))
+	if c := b.locate(x, y); c != "" {
+		return c
+	}
+	const d = borderGap * scale
+	for _, off := range [][2]int32{{d, 0}, {-d, 0}, {0, d}, {0, -d}, {d, d}, {d, -d}, {-d, d}, {-d, -d}} {
+		if c := b.locate(x+off[0], y+off[1]); c != "" {
+			return c
+		}
+	}
+	return ""
+}
+
+// loadBoundaries decodes boundary.bin generated by the gen command.
+func loadBoundaries(data []byte) (b *boundaries, e error) {
+	z, e := gzip.NewReader(bytes.NewReader(data))
+	if e != nil {
+		return nil, e
+	}
+	r := bufio.NewReader(z)
+	var readErr error
+	uvarint := func() int {
+		v, e := binary.ReadUvarint(r)
+		readErr = errors.Join(readErr, e)
+		return int(v)
+	}
+	varint := func() int64 {
+		v, e := binary.ReadVarint(r)
+		readErr = errors.Join(readErr, e)
+		return v
+	}
+
+	b = &boundaries{grid: map[[2]int16][]int{}}
+	nCountries := uvarint()
+	for range nCountries {
+		var code [2]byte
+		if _, e := io.ReadFull(r, code[:]); e != nil {
+			return nil, e
+		}
+		nPolygons := uvarint()
+		for range nPolygons {
+			p := polygon{
+				country: string(code[:]),
+				min:     [2]int32{math.MaxInt32, math.MaxInt32},
+				max:     [2]int32{math.MinInt32, math.MinInt32},
+			}
+			nRings := uvarint()
+			for range nRings {
+				rg := make(ring, uvarint())
+				var prev [2]int64
+				for i := range rg {
+					prev[0] += varint()
+					prev[1] += varint()
+					rg[i] = [2]int32{int32(prev[0]), int32(prev[1])}
+					for k := range 2 {
+						p.min[k], p.max[k] = min(p.min[k], rg[i][k]), max(p.max[k], rg[i][k])
+					}
+				}
+				p.rings = append(p.rings, rg)
+			}
+			if readErr != nil {
+				return nil, readErr
+			}
+			b.polygons = append(b.polygons, p)
+		}
+	}
+
+	for i, p := range b.polygons {
+		lo, hi := gridCell(p.min[0], p.min[1]), gridCell(p.max[0], p.max[1])
+		for gx := lo[0]; gx <= hi[0]; gx++ {
+			for gy := lo[1]; gy <= hi[1]; gy++ {
+				b.grid[[2]int16{gx, gy}] = append(b.grid[[2]int16{gx, gy}], i)
+			}
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,78 @@
+// Package country derives ISO 3166 country codes from geographical positions.
+//
+// Country boundaries are embedded in the executable as boundary.bin, generated by the gen command.
+// They are polygons from timezone-boundary-builder, which are built from OpenStreetMap administrative
+// boundaries, grouped by country according to zone.tab of the IANA tz database, and simplified with
+// 0.002 degree (about 200 m) tolerance. Positions in territorial waters close to the coast may be
+// located within a country; positions farther out at sea have no country.
+//
+// The boundary dataset contains information from OpenStreetMap, © OpenStreetMap contributors, and is
+// made available under the Open Database License; see the NOTICE file in the repository root.
+package country
+
+import (
+	"sync"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/logging"
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/biter777/countries"
+	"go.uber.org/zap"
+)
+
+var logger = logging.New("country")
+
+var (
+	loadOnce sync.Once
+	world    *boundaries
+)
+
+func load() {
+	var e error
+	if world, e = loadBoundaries(boundaryBin); e != nil {
+		logger.Error("load error", zap.Error(e))
+	}
+}
+
+// Locate returns the ISO 3166 alpha-2 country code at a position, or empty string if unknown.
+func Locate(pos model.LonLat) string {
+	loadOnce.Do(load)
+	if world == nil {
+		return ""
+	}
+	return world.Locate(pos)
+}
+
+// Normalize returns an upper case ISO 3166 alpha-2 country code, or empty string if invalid.
+func Normalize(code string) string {
+	c := countries.ByName(code)
+	if !c.IsValid() {
+		return ""
+	}
+	return c.Alpha2()
+}
+
+// Continent codes.
+var continentCodes = map[countries.RegionCode]string{
+	countries.RegionAF:         "AF",
+	countries.RegionAN:         "AN",
+	countries.RegionAntarctica: "AN",
+	countries.RegionAS:         "AS",
+	countries.RegionEU:         "EU",
+	countries.RegionNA:         "NA",
+	countries.RegionOC:         "OC",
+	countries.RegionSA:         "SA",
+}
+
+// Continent returns the continent code of a country: AF, AN, AS, EU, NA, OC, or SA.
+func Continent(code string) string {
+	return continentCodes[countries.ByName(code).Region()]
+}
+
+func init() {
+	for _, c := range countries.All() {
+		if continent := continentCodes[c.Region()]; continent != "" {
+			name := model.ContinentPrefix + continent
+			model.Regions[name] = append(model.Regions[name], c.Alpha2())
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package country_test
+
+import (
+	"testing"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/country"
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocate(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("CN", country.Locate(model.LonLat{121.4737, 31.2304}))
+	assert.Equal("FR", country.Locate(model.LonLat{2.3522, 48.8566}))
+	assert.Equal("NO", country.Locate(model.LonLat{10.7522, 59.9139}))
+	assert.Equal("US", country.Locate(model.LonLat{-77.0369, 38.9072}))
+	assert.Equal("", country.Locate(model.LonLat{-30.0, 0.0}))
+
+	// border areas
+	assert.Equal("CH", country.Locate(model.LonLat{6.1432, 46.2044}))   // Geneva
+	assert.Equal("FR", country.Locate(model.LonLat{7.7521, 48.5734}))   // Strasbourg
+	assert.Equal("DE", country.Locate(model.LonLat{7.8133, 48.5724}))   // Kehl
+	assert.Equal("US", country.Locate(model.LonLat{-83.0458, 42.3314})) // Detroit
+	assert.Equal("CA", country.Locate(model.LonLat{-83.0364, 42.3149})) // Windsor
+	assert.Equal("SG", country.Locate(model.LonLat{103.8198, 1.3521}))  // Singapore
+	assert.Equal("MY", country.Locate(model.LonLat{103.7414, 1.4927}))  // Johor Bahru
+	assert.Equal("MN", country.Locate(model.LonLat{114.5, 48.07}))      // Choibalsan
+}
+
+func BenchmarkLocate(b *testing.B) {
+	country.Locate(model.LonLat{})
+	for i := 0; b.Loop(); i++ {
+		country.Locate(model.LonLat{float64(i%360 - 180), float64(i%140 - 70)})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("JP", country.Normalize("jp"))
+	assert.Equal("DE", country.Normalize("DEU"))
+	assert.Equal("", country.Normalize("ZZ"))
+	assert.Equal("AS", country.Continent("JP"))
+	assert.Equal("EU", country.Continent("fr"))
+	assert.Contains(model.Regions["continent:SA"], "BR")
+	assert.NotContains(model.Regions["continent:SA"], "SA")
+}
+
+func TestContinentRegion(t *testing.T) {
+	assert := assert.New(t)
+
+	// two-letter codes are countries, even if they collide with continent codes
+	assert.Equal([]string{"AS", "NA", "SA"}, model.ParseQueries("region=SA,na&region=as")[0].Countries)
+
+	q := model.ParseQueries("region=continent:SA")[0]
+	assert.Contains(q.Countries, "BR")
+	assert.NotContains(q.Countries, "SA")
+	assert.Equal(q.Countries, model.ParseQueries("region=Continent:sa")[0].Countries)
+
+	q = model.ParseQueries("region=continent:AS")[0]
+	assert.Contains(q.Countries, "SA")
+	assert.Contains(q.Countries, "JP")
+}
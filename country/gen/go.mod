@@ -0,0 +1,9 @@
+module github.com/11th-ndn-hackathon/ndn-fch/country/gen
+
+go 1.24
+
+require (
+	github.com/ringsaturn/tzf v1.0.2
+	github.com/ringsaturn/tzf-rel v0.0.2023-d1
+	google.golang.org/protobuf v1.36.9
+)
@@ -0,0 +1,192 @@
+// Command gen generates the embedded country boundary dataset.
+//
+// Country polygons are timezone polygons from timezone-boundary-builder, which are built from
+// OpenStreetMap administrative boundaries, grouped by country according to zone.tab.
+// Usage: go generate ./country
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"flag"
+	"log"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	tzfrel "github.com/ringsaturn/tzf-rel"
+	pb "github.com/ringsaturn/tzf/gen/go/tzf/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	output    = flag.String("o", "boundary.bin", "output file")
+	zoneTab   = flag.String("zonetab", "zone.tab", "zone.tab file mapping timezones to countries")
+	tolerance = flag.Float64("tolerance", 0.002, "simplification tolerance in degrees")
+)
+
+// legacyZones maps timezones that are in timezone-boundary-builder but no longer in zone.tab.
+var legacyZones = map[string]string{
+	"Asia/Choibalsan": "MN",
+}
+
+// scale converts degrees to stored integer units.
+const scale = 1e4
+
+type point [2]float64
+
+func main() {
+	flag.Parse()
+
+	zoneCountry := readZoneTab(*zoneTab)
+	for zone, cc := range legacyZones {
+		zoneCountry[zone] = cc
+	}
+
+	var tzs pb.Timezones
+	if e := proto.Unmarshal(tzfrel.FullData, &tzs); e != nil {
+		log.Fatal(e)
+	}
+
+	countries := map[string][][][]point{}
+	for _, tz := range tzs.GetTimezones() {
+		cc := zoneCountry[tz.GetName()]
+		if cc == "" {
+			if !strings.HasPrefix(tz.GetName(), "Etc/") {
+				log.Printf("timezone %s has no country", tz.GetName())
+			}
+			continue
+		}
+		for _, poly := range tz.GetPolygons() {
+			exterior := simplify(ring(poly.GetPoints()), *tolerance)
+			if len(exterior) < 4 {
+				continue
+			}
+			rings := [][]point{exterior}
+			for _, hole := range poly.GetHoles() {
+				if h := simplify(ring(hole.GetPoints()), *tolerance); len(h) >= 4 {
+					rings = append(rings, h)
+				}
+			}
+			countries[cc] = append(countries[cc], rings)
+		}
+	}
+
+	f, e := os.Create(*output)
+	if e != nil {
+		log.Fatal(e)
+	}
+	defer f.Close()
+	z, _ := gzip.NewWriterLevel(f, gzip.BestCompression)
+	defer z.Close()
+	w := bufio.NewWriter(z)
+	defer w.Flush()
+
+	codes := make([]string, 0, len(countries))
+	for cc := range countries {
+		codes = append(codes, cc)
+	}
+	slices.Sort(codes)
+
+	buf := make([]byte, binary.MaxVarintLen64)
+	writeUvarint := func(v int) { w.Write(buf[:binary.PutUvarint(buf, uint64(v))]) }
+	writeVarint := func(v int64) { w.Write(buf[:binary.PutVarint(buf, v)]) }
+
+	nPoints := 0
+	writeUvarint(len(codes))
+	for _, cc := range codes {
+		w.WriteString(cc)
+		writeUvarint(len(countries[cc]))
+		for _, rings := range countries[cc] {
+			writeUvarint(len(rings))
+			for _, r := range rings {
+				writeUvarint(len(r))
+				var prev [2]int64
+				for _, p := range r {
+					q := [2]int64{int64(math.Round(p[0] * scale)), int64(math.Round(p[1] * scale))}
+					writeVarint(q[0] - prev[0])
+					writeVarint(q[1] - prev[1])
+					prev = q
+				}
+				nPoints += len(r)
+			}
+		}
+	}
+	log.Printf("%d countries, %d points", len(codes), nPoints)
+}
+
+func readZoneTab(filename string) (m map[string]string) {
+	body, e := os.ReadFile(filename)
+	if e != nil {
+		log.Fatal(e)
+	}
+	m = map[string]string{}
+	for _, line := range strings.Split(string(body), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 3 && !strings.HasPrefix(line, "#") {
+			m[fields[2]] = fields[0]
+		}
+	}
+	return m
+}
+
+func ring(points []*pb.Point) (r []point) {
+	for _, p := range points {
+		r = append(r, point{float64(p.GetLng()), float64(p.GetLat())})
+	}
+	return r
+}
+
+// simplify applies Douglas-Peucker simplification to a ring.
+func simplify(r []point, tolerance float64) []point {
+	if len(r) < 4 {
+		return r
+	}
+	keep := make([]bool, len(r))
+	keep[0], keep[len(r)-1] = true, true
+	// a closed ring has identical endpoints, so it is split at its farthest point first
+	far, farDist := 0, 0.0
+	for i, p := range r {
+		if d := math.Hypot(p[0]-r[0][0], p[1]-r[0][1]); d > farDist {
+			far, farDist = i, d
+		}
+	}
+	keep[far] = true
+	dp(r, 0, far, tolerance, keep)
+	dp(r, far, len(r)-1, tolerance, keep)
+
+	var out []point
+	for i, p := range r {
+		if keep[i] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func dp(r []point, first, last int, tolerance float64, keep []bool) {
+	for last-first > 1 {
+		index, maxDist := 0, 0.0
+		for i := first + 1; i < last; i++ {
+			if d := segmentDistance(r[i], r[first], r[last]); d > maxDist {
+				index, maxDist = i, d
+			}
+		}
+		if maxDist <= tolerance {
+			return
+		}
+		keep[index] = true
+		dp(r, first, index, tolerance, keep)
+		first = index
+	}
+}
+
+func segmentDistance(p, a, b point) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = max(0, min(1, ((p[0]-a[0])*dx+(p[1]-a[1])*dy)/l))
+	}
+	return math.Hypot(p[0]-a[0]-t*dx, p[1]-a[1]-t*dy)
+}
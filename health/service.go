@@ -39,6 +39,16 @@ func (response ProbeResponse) Count() (nSuccess, nFailure int) {
 	return
 }
 
+// MinRTT returns the minimum RTT among probes with OK==true, in milliseconds.
+func (response ProbeResponse) MinRTT() (rtt float64, ok bool) {
+	for _, res := range response.Probes {
+		if res.OK && res.RTT > 0 && (!ok || res.RTT < rtt) {
+			rtt, ok = res.RTT, true
+		}
+	}
+	return
+}
+
 // Service represents a service that can probe router health.
 type Service interface {
 	Probe(ctx context.Context, req ProbeRequest) (res ProbeResponse, e error)
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"slices"
+	"strings"
+)
+
+// TagCountry is a tag key that overrides the country derived from router position.
+const TagCountry = "country"
+
+// ContinentPrefix is the prefix of continent region names, such as "continent:EU".
+const ContinentPrefix = "continent:"
+
+// Regions maps region names to ISO 3166 alpha-2 country codes.
+// The country package registers continents with ContinentPrefix; more regions may be configured.
+// Region names are case insensitive and must not be two letters, which are always country codes.
+var Regions = map[string][]string{}
+
+// CountryMode selects how Query.Country affects router selection.
+type CountryMode string
+
+// CountryMode values.
+const (
+	CountryAny     CountryMode = ""
+	CountryPrefer  CountryMode = "prefer"  // routers in client country are placed first
+	CountryRequire CountryMode = "require" // only routers in client country are returned
+)
+
+func parseCountryMode(value string) CountryMode {
+	switch value {
+	case "1", string(CountryPrefer):
+		return CountryPrefer
+	case string(CountryRequire):
+		return CountryRequire
+	}
+	return CountryAny
+}
+
+// lookupRegion finds a region by name, case insensitively.
+func lookupRegion(name string) (list []string, ok bool) {
+	if list, ok = Regions[name]; ok {
+		return list, true
+	}
+	for k, list := range Regions {
+		if strings.EqualFold(k, name) {
+			return list, true
+		}
+	}
+	return nil, false
+}
+
+// resolveRegions resolves region names and country codes to country codes.
+// A two-letter name is a country code; other names are looked up in Regions.
+// Unknown names are kept in upper case, so that they match no router.
+func resolveRegions(values []string) (codes []string) {
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if len(name) == 2 {
+				codes = append(codes, strings.ToUpper(name))
+			} else if list, ok := lookupRegion(name); ok {
+				codes = append(codes, list...)
+			} else if name != "" {
+				codes = append(codes, strings.ToUpper(name))
+			}
+		}
+	}
+	slices.Sort(codes)
+	return slices.Compact(codes)
+}
+
+// matchCountry determines whether a router satisfies country constraints.
+func (q Query) matchCountry(router RouterAvail) bool {
+	if len(q.Countries) > 0 && !slices.Contains(q.Countries, router.Country) {
+		return false
+	}
+	if q.SameCountry == CountryRequire && (q.Country == "" || router.Country != q.Country) {
+		return false
+	}
+	return true
+}
+
+// preferCountry moves routers in client country before other candidates, keeping relative order.
+func preferCountry(candidates []ScoredRouter, country string) []ScoredRouter {
+	var same, others []ScoredRouter
+	for _, c := range candidates {
+		if country != "" && c.Country == country {
+			same = append(same, c)
+		} else {
+			others = append(others, c)
+		}
+	}
+	return append(same, others...)
+}
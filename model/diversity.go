@@ -0,0 +1,77 @@
+package model
+
+import (
+	"math"
+	"strings"
+)
+
+// Router similarity components for diversity selection.
+const (
+	similarSite      = 1.0 // routers within sameSiteDistance
+	similarAdjacent  = 0.6 // routers directly linked in Neighbors graph
+	similarNetwork   = 0.3 // routers with the same first prefix component
+	sameSiteDistance = 50  // kilometers
+)
+
+func networkOf(prefix string) string {
+	network, _, _ := strings.Cut(strings.TrimPrefix(prefix, "/"), "/")
+	return network
+}
+
+// similarity returns how likely two routers would fail together, between 0.0 and 1.0.
+func similarity(a, b RouterAvail) float64 {
+	switch {
+	case Distance(a.Position(), b.Position()) < sameSiteDistance:
+		return similarSite
+	case hasNeighbor(a, b.ID()) || hasNeighbor(b, a.ID()):
+		return similarAdjacent
+	case a.Prefix() != "" && networkOf(a.Prefix()) == networkOf(b.Prefix()):
+		return similarNetwork
+	}
+	return 0
+}
+
+func hasNeighbor(r RouterAvail, id string) bool {
+	_, ok := r.Neighbors()[id]
+	return ok
+}
+
+// diversify greedily reorders sorted candidates so that the first count entries balance
+// proximity against failure diversity.
+// Each step picks the candidate that minimizes:
+//
+//	(1-diversity) * normalized score + diversity * max similarity to already selected routers
+//
+// If explain is set, the similarity is recorded as a score component.
+func diversify(candidates []ScoredRouter, count int, diversity float64, explain bool) []ScoredRouter {
+	if len(candidates) <= 1 {
+		return candidates
+	}
+	lo, hi := candidates[0].Score, candidates[len(candidates)-1].Score
+	normalize := func(score float64) float64 {
+		if hi <= lo {
+			return 0
+		}
+		return (score - lo) / (hi - lo)
+	}
+
+	for n := 1; n < min(count, len(candidates)); n++ {
+		best, bestCost, bestSim := n, math.Inf(1), 0.0
+		for i := n; i < len(candidates); i++ {
+			sim := 0.0
+			for _, selected := range candidates[:n] {
+				sim = max(sim, similarity(candidates[i].RouterAvail, selected.RouterAvail))
+			}
+			if cost := (1-diversity)*normalize(candidates[i].Score) + diversity*sim; cost < bestCost {
+				best, bestCost, bestSim = i, cost, sim
+			}
+		}
+		if explain {
+			candidates[best].setComponent("similarity", bestSim)
+		}
+		picked := candidates[best]
+		copy(candidates[n+1:best+1], candidates[n:best])
+		candidates[n] = picked
+	}
+	return candidates
+}
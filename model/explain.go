@@ -0,0 +1,71 @@
+package model
+
+// FilterReason indicates why a router was excluded by query filters.
+type FilterReason string
+
+// FilterReason values.
+const (
+	FilterTransport    FilterReason = "transport"    // router does not support the transport
+	FilterFamily       FilterReason = "family"       // router does not support the transport in requested IP families
+	FilterAvailability FilterReason = "availability" // transport is not available according to health probes
+	FilterNetwork      FilterReason = "network"      // router prefix is not in the requested network
+	FilterDistance     FilterReason = "maxdist"      // router is farther than the maximum distance
+	FilterRTT          FilterReason = "maxrtt"       // router RTT exceeds the maximum RTT
+	FilterExcluded     FilterReason = "exclude"      // router is excluded by ID or hostname
+	FilterTag          FilterReason = "tag"          // router tags do not match
+	FilterCountry      FilterReason = "country"      // router is outside the required country or region
+	FilterPolicy       FilterReason = "policy"       // router is denied to the client by access policy
+)
+
+// shortfallReasons lists FilterReason in the order they are reported as QueryShortfall.Reason,
+// most specific to the query first.
+var shortfallReasons = []FilterReason{FilterDistance, FilterRTT, FilterExcluded, FilterTag, FilterCountry, FilterNetwork, FilterPolicy, FilterAvailability, FilterFamily, FilterTransport}
+
+// QueryResult is the result of Query.Execute.
+type QueryResult struct {
+	// Routers contains selected routers, best first.
+	Routers []ScoredRouter
+
+	// Explain contains every router in the availability list, if Query.Explain is set.
+	// Ranked candidates appear first in rank order, followed by filtered routers.
+	Explain []ExplainEntry
+
+	// Shortfall is set if fewer than Query.Count routers qualified.
+	Shortfall *QueryShortfall
+}
+
+// QueryShortfall explains why a query returned fewer routers than requested.
+type QueryShortfall struct {
+	Transport  TransportType   `json:"transport"`
+	Transports []TransportType `json:"transports,omitempty"` // acceptable transports, if more than one
+	Requested  int             `json:"requested"`
+	Returned   int             `json:"returned"`
+
+	// Reason is the most specific filter that excluded routers, empty if no router was filtered.
+	Reason FilterReason `json:"reason,omitempty"`
+	// Filtered is the number of routers excluded by each filter.
+	Filtered map[FilterReason]int `json:"filtered"`
+}
+
+// ExplainEntry describes what happened to a router during query execution.
+type ExplainEntry struct {
+	ID        string        `json:"id"`
+	Country   string        `json:"country,omitempty"`
+	Transport TransportType `json:"transport,omitempty"` // chosen transport of a candidate
+	Filtered  FilterReason  `json:"filtered,omitempty"`
+	Distance  float64       `json:"distance"` // kilometers
+
+	Score      *float64           `json:"score,omitempty"`
+	Components map[string]float64 `json:"scoreComponents,omitempty"`
+	Saturated  bool               `json:"saturated,omitempty"`
+
+	Rank     int  `json:"rank,omitempty"` // 1-based rank among candidates, zero if filtered
+	Selected bool `json:"selected"`
+}
+
+// QueryExplanation is part of QueryResponse, containing ExplainEntry of one query.
+type QueryExplanation struct {
+	Transport  TransportType   `json:"transport"`
+	Transports []TransportType `json:"transports,omitempty"` // acceptable transports, if more than one
+	Candidates []ExplainEntry  `json:"candidates"`
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Vivaldi algorithm parameters.
+const (
+	vivaldiCE        = 0.25 // error tuning constant
+	vivaldiCC        = 0.25 // timestep tuning constant
+	vivaldiErrorMax  = 1.5
+	vivaldiHeightMin = 0.01 // milliseconds
+)
+
+// NetCoord is a Vivaldi network coordinate with height vector.
+// Units are milliseconds.
+// https://pdos.csail.mit.edu/papers/vivaldi:sigcomm/paper.pdf
+type NetCoord struct {
+	Vec    [2]float64 `json:"vec"`
+	Height float64    `json:"height"`
+	Error  float64    `json:"error"`
+}
+
+// NewNetCoord returns an initial network coordinate.
+func NewNetCoord() NetCoord {
+	return NetCoord{
+		Height: vivaldiHeightMin,
+		Error:  vivaldiErrorMax,
+	}
+}
+
+func (c NetCoord) vecDistance(other NetCoord) float64 {
+	return math.Hypot(c.Vec[0]-other.Vec[0], c.Vec[1]-other.Vec[1])
+}
+
+// PredictRTT returns predicted RTT between two coordinates in milliseconds.
+func PredictRTT(a, b NetCoord) float64 {
+	return a.vecDistance(b) + a.Height + b.Height
+}
+
+// Update adjusts this coordinate after observing RTT to a remote node.
+func (c *NetCoord) Update(remote NetCoord, rtt float64) {
+	if rtt <= 0 || math.IsNaN(rtt) {
+		return
+	}
+
+	dist := PredictRTT(*c, remote)
+	w := c.Error / (c.Error + remote.Error)
+	es := math.Abs(dist-rtt) / rtt
+	c.Error = min(vivaldiErrorMax, es*vivaldiCE*w+c.Error*(1-vivaldiCE*w))
+
+	force := vivaldiCC * w * (rtt - dist)
+	unit := [2]float64{c.Vec[0] - remote.Vec[0], c.Vec[1] - remote.Vec[1]}
+	mag := math.Hypot(unit[0], unit[1])
+	if mag < 1e-6 {
+		theta := rand.Float64() * 2 * math.Pi
+		unit = [2]float64{math.Cos(theta), math.Sin(theta)}
+	} else {
+		unit[0] /= mag
+		unit[1] /= mag
+		c.Height = max(vivaldiHeightMin, c.Height+(c.Height+remote.Height)*force/mag)
+	}
+	c.Vec[0] += unit[0] * force
+	c.Vec[1] += unit[1] * force
+}
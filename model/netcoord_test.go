@@ -0,0 +1,76 @@
+package model_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetCoord(t *testing.T) {
+	assert := assert.New(t)
+
+	// nodes on a line, RTT proportional to distance plus 2ms access delay at each end
+	pos := []float64{0, 20, 50, 120}
+	rtt := func(i, j int) float64 {
+		return math.Abs(pos[i]-pos[j]) + 4
+	}
+	coords := make([]model.NetCoord, len(pos))
+	for i := range coords {
+		coords[i] = model.NewNetCoord()
+	}
+	for range 200 {
+		for i := range coords {
+			for j := range coords {
+				if i != j {
+					coords[i].Update(coords[j], rtt(i, j))
+				}
+			}
+		}
+	}
+
+	for i := range coords {
+		for j := range coords {
+			if i != j {
+				assert.InEpsilon(rtt(i, j), model.PredictRTT(coords[i], coords[j]), 0.15, "%d-%d", i, j)
+			}
+		}
+	}
+}
+
+func TestQueryRankVivaldi(t *testing.T) {
+	assert := assert.New(t)
+
+	udp4 := model.TransportIPFamily{Transport: model.TransportUDP, Family: model.IPv4}
+	avail := []model.RouterAvail{
+		{
+			Router:    testRouter{id: "A", pos: model.LonLat{121.0, 31.0}},
+			Available: map[model.TransportIPFamily]bool{udp4: true},
+			Coord:     &model.NetCoord{Vec: [2]float64{0, 0}, Height: 1},
+		},
+		{
+			Router:    testRouter{id: "B", pos: model.LonLat{127.0, 37.5}},
+			Available: map[model.TransportIPFamily]bool{udp4: true},
+			Coord:     &model.NetCoord{Vec: [2]float64{200, 0}, Height: 1},
+		},
+		{
+			Router:    testRouter{id: "C", pos: model.LonLat{139.7, 35.7}},
+			Available: map[model.TransportIPFamily]bool{udp4: true},
+			Coord:     &model.NetCoord{Vec: [2]float64{20, 0}, Height: 1},
+		},
+	}
+
+	q := model.ParseQueries("k=3&cap=udp&lon=121.4737&lat=31.2304&rank=vivaldi")[0]
+	assert.Equal(model.RankVivaldi, q.Rank)
+	res := q.Execute(avail).Routers
+	if assert.Len(res, 3) {
+		assert.Equal([]string{"A", "C", "B"}, []string{res[0].ID(), res[1].ID(), res[2].ID()})
+	}
+
+	q = model.ParseQueries("k=1&cap=udp&lon=121.4737&lat=31.2304&rank=vivaldi&coord=190,0,1")[0]
+	res = q.Execute(avail).Routers
+	if assert.Len(res, 1) {
+		assert.Equal("B", res[0].ID())
+	}
+}
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// AnyNetwork is a network name that matches every router.
+const AnyNetwork = "*"
+
+// NetworkAliases maps friendly network names to one or more NDN prefixes.
+// A network name without alias refers to the "/<name>/" prefix.
+var NetworkAliases = map[string][]string{}
+
+func normalizeNetworkPrefix(prefix string) string {
+	return "/" + strings.Trim(prefix, "/") + "/"
+}
+
+// resolveNetwork resolves a network name to NDN prefixes.
+func resolveNetwork(name string) (prefixes []string) {
+	name = strings.TrimSpace(name)
+	switch name {
+	case "", "/":
+		return nil
+	case AnyNetwork:
+		return []string{AnyNetwork}
+	}
+	if alias, ok := NetworkAliases[strings.Trim(name, "/")]; ok {
+		for _, prefix := range alias {
+			prefixes = append(prefixes, normalizeNetworkPrefix(prefix))
+		}
+		return prefixes
+	}
+	return []string{normalizeNetworkPrefix(name)}
+}
+
+// parseNetworks parses network parameter values.
+// Each value is a comma-separated list of network names at the same priority.
+// A name starting with "!" excludes the network.
+func parseNetworks(values []string) (groups [][]string, exclude []string) {
+	for _, value := range values {
+		var group []string
+		for _, name := range strings.Split(value, ",") {
+			if name, ok := strings.CutPrefix(strings.TrimSpace(name), "!"); ok {
+				exclude = append(exclude, resolveNetwork(name)...)
+			} else {
+				group = append(group, resolveNetwork(name)...)
+			}
+		}
+		if len(group) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups, exclude
+}
+
+func matchNetworkPrefix(routerPrefix, prefix string) bool {
+	return prefix == AnyNetwork || strings.HasPrefix(routerPrefix, prefix)
+}
+
+// networkRank returns the priority of the first network group that contains the router,
+// or -1 if the router does not match any network group or is in an excluded network.
+func (q Query) networkRank(router Router) int {
+	prefix := router.Prefix()
+	if slices.ContainsFunc(q.ExcludeNetworks, func(x string) bool { return matchNetworkPrefix(prefix, x) }) {
+		return -1
+	}
+	if len(q.Networks) == 0 {
+		return 0
+	}
+	return slices.IndexFunc(q.Networks, func(group []string) bool {
+		return slices.ContainsFunc(group, func(x string) bool { return matchNetworkPrefix(prefix, x) })
+	})
+}
+
+// prioritizeNetworks orders candidates by network priority, keeping relative order within each network group.
+func prioritizeNetworks(candidates []ScoredRouter, q Query) []ScoredRouter {
+	slices.SortStableFunc(candidates, func(a, b ScoredRouter) int {
+		return cmp.Compare(q.networkRank(a), q.networkRank(b))
+	})
+	return candidates
+}
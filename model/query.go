@@ -1,54 +1,395 @@
 package model
 
 import (
-	"cmp"
+	"errors"
 	"net/url"
 	"slices"
 	"strconv"
 	"strings"
 )
 
+// MaxCount is the server-side upper bound of Query.Count; zero means unlimited.
+var MaxCount = 32
+
 // Query represents an API query.
 type Query struct {
 	Count     int
 	Transport TransportType
-	IPv4      bool
-	IPv6      bool
-	Position  LonLat
-	Network   string
+
+	// Transports is a preference-ordered list of acceptable transports, starting with Transport.
+	// Each router is returned with the first transport that is available, and routers are ranked
+	// together across transports. If empty, only Transport is acceptable.
+	Transports []TransportType
+
+	IPv4     bool
+	IPv6     bool
+	Position LonLat
+
+	// Networks contains priority-ordered groups of NDN prefixes.
+	// If not empty, a router must be in one of these networks, and routers in an earlier group
+	// are placed before routers in a later group. AnyNetwork matches every router.
+	Networks [][]string
+	// ExcludeNetworks contains NDN prefixes of networks that must not be returned.
+	ExcludeNetworks []string
+
+	// Country is the client ISO 3166 alpha-2 country code, empty if unknown.
+	Country string
+	// SameCountry selects whether routers in client country are preferred or required.
+	SameCountry CountryMode
+	// Countries, if not empty, restricts routers to these ISO 3166 alpha-2 country codes.
+	Countries []string
+
+	// Tags contains tag clauses. A router must satisfy every clause; a clause is satisfied
+	// if any of its TagMatch matches.
+	Tags [][]TagMatch
+
+	// MaxDistance is the maximum geographical distance in kilometers; zero means unlimited.
+	MaxDistance float64
+	// MaxRTT is the maximum RTT between client and router in milliseconds; zero means unlimited.
+	// RTT is predicted from network coordinates if Coord is set and the router has a coordinate,
+	// otherwise it is estimated from geographical distance.
+	MaxRTT float64
+
+	// Exclude contains router IDs or hostnames that must not be returned.
+	Exclude []string
+	// Prefer contains router IDs that are moved to the front, in order, if they are candidates.
+	Prefer []string
+
+	// Rank selects a registered Ranker.
+	Rank RankMode
+	// Base selects the ScoreRanker underlying RankRandom and RankDiverse.
+	Base RankMode
+
+	// Coord is the client network coordinate for RankVivaldi.
+	// If nil, the network coordinate of the geographically nearest router is used.
+	Coord *NetCoord
+
+	// Diversity is between 0.0 and 1.0, trading proximity for failure diversity in RankDiverse.
+	Diversity float64
+
+	// Spread is the band above the best score for weighted random selection in RankRandom,
+	// in units of the base ranking score (kilometers for RankDistance).
+	Spread float64
+	// Seed is the random seed for weighted random selection; zero means unseeded.
+	Seed int64
+
+	// Client is a client key for sticky assignment, such as client IP prefix.
+	// If not empty and Rank is a ScoreRanker, the client is consistently assigned among routers
+	// within StickyTolerance of the best score.
+	Client string
+
+	// Load reports saturated routers, whose score is penalized so that they rank after other candidates.
+	// If nil, router load is not considered.
+	Load LoadChecker
+
+	// Policy restricts routers that may be returned to the client.
+	// If nil, every router is permitted.
+	Policy AccessPolicy
+
+	// Explain requests a detailed QueryResult.Explain.
+	Explain bool
+
+	// Index is a SpatialIndex built from the availability list passed to Execute.
+	// If set, queries ranked by RankDistance without sticky assignment or explanation are
+	// answered from the index instead of sorting the entire list.
+	Index *SpatialIndex
+}
+
+// LoadChecker reports whether a router has exceeded its capacity limits.
+type LoadChecker interface {
+	Saturated(r Router) bool
+}
+
+// AccessPolicy restricts routers that may be returned to a client.
+type AccessPolicy interface {
+	// Deny returns the name of the rule that denies a router to the client, or empty string if permitted.
+	Deny(r Router) string
+
+	// Key identifies the decisions of this AccessPolicy.
+	// AccessPolicy instances with the same Key must deny the same routers.
+	Key() string
+}
+
+func (q Query) families() (families []IPFamily) {
+	if q.IPv4 {
+		families = append(families, IPv4)
+	}
+	if q.IPv6 {
+		families = append(families, IPv6)
+	}
+	return families
+}
+
+func (q Query) transports() []TransportType {
+	if len(q.Transports) == 0 {
+		return []TransportType{q.Transport}
+	}
+	return q.Transports
 }
 
-func (q Query) matchTransport(router RouterAvail) bool {
-	switch {
-	case q.IPv4 && router.Available[TransportIPFamily{q.Transport, IPv4}]:
-		return true
-	case q.IPv6 && router.Available[TransportIPFamily{q.Transport, IPv6}]:
-		return true
+// fallbackTransports returns Transports if there is more than one acceptable transport, otherwise nil.
+func (q Query) fallbackTransports() []TransportType {
+	if len(q.Transports) <= 1 {
+		return nil
+	}
+	return q.Transports
+}
+
+// transportOf returns the first acceptable transport that is available on a router in requested IP families.
+func (q Query) transportOf(router RouterAvail) (tr TransportType, ok bool) {
+	for _, tr := range q.transports() {
+		for _, af := range q.families() {
+			if router.Available[TransportIPFamily{tr, af}] {
+				return tr, true
+			}
+		}
+	}
+	return "", false
+}
+
+// filter determines whether a router passes query filters.
+// Returns empty FilterReason if the router is a candidate.
+func (q Query) filter(router RouterAvail) FilterReason {
+	_, ok := q.transportOf(router)
+	if !ok {
+		supported, anyFamily := false, false
+		for _, tr := range q.transports() {
+			for _, af := range IPFamilies {
+				if router.ConnectString(TransportIPFamily{tr, af}) != "" {
+					anyFamily = true
+					supported = supported || slices.Contains(q.families(), af)
+				}
+			}
+		}
+		switch {
+		case supported:
+			return FilterAvailability
+		case anyFamily:
+			return FilterFamily
+		default:
+			return FilterTransport
+		}
+	}
+
+	if q.Policy != nil && q.Policy.Deny(router.Router) != "" {
+		return FilterPolicy
+	}
+	if q.networkRank(router.Router) < 0 {
+		return FilterNetwork
+	}
+	if !q.matchTags(router.Router) {
+		return FilterTag
+	}
+	if !q.matchCountry(router) {
+		return FilterCountry
+	}
+	if q.isExcluded(router.Router) {
+		return FilterExcluded
+	}
+	if q.MaxDistance > 0 || q.MaxRTT > 0 {
+		dist := Distance(q.Position, router.Position())
+		if q.MaxDistance > 0 && dist > q.MaxDistance {
+			return FilterDistance
+		}
+		if q.MaxRTT > 0 && q.clientRTT(router, dist) > q.MaxRTT {
+			return FilterRTT
+		}
+	}
+	return ""
+}
+
+// clientRTT estimates RTT between client and router in milliseconds.
+// RouterAvail.RTT is not used, because it is measured from the health probe rather than the client.
+func (q Query) clientRTT(router RouterAvail, dist float64) float64 {
+	if q.Coord != nil && router.Coord != nil {
+		return PredictRTT(*q.Coord, *router.Coord)
+	}
+	return dist / 100
+}
+
+func (q Query) isExcluded(router Router) bool {
+	if len(q.Exclude) == 0 {
+		return false
+	}
+	match := func(value string) bool {
+		return slices.ContainsFunc(q.Exclude, func(x string) bool { return strings.EqualFold(x, value) })
+	}
+	return match(router.ID()) || slices.ContainsFunc(RouterHosts(router), match)
+}
+
+// prefer moves preferred routers to the front, in the order of preference, keeping relative order of others.
+func prefer(candidates []ScoredRouter, ids []string) []ScoredRouter {
+	var preferred, others []ScoredRouter
+	for _, id := range ids {
+		if i := slices.IndexFunc(candidates, func(c ScoredRouter) bool { return c.ID() == id }); i >= 0 {
+			preferred = append(preferred, candidates[i])
+		}
 	}
-	return false
+	for _, c := range candidates {
+		if !slices.ContainsFunc(preferred, func(p ScoredRouter) bool { return p.ID() == c.ID() }) {
+			others = append(others, c)
+		}
+	}
+	return append(preferred, others...)
 }
 
-func (q Query) matchNetwork(router RouterAvail) bool {
-	return q.Network == "" || strings.HasPrefix(router.Prefix(), q.Network)
+// executeIndexed executes a query with RankDistance using the spatial index.
+func (q Query) executeIndexed(avail []RouterAvail) (res QueryResult) {
+	var tfs []TransportIPFamily
+	for _, tr := range q.transports() {
+		for _, af := range q.families() {
+			tfs = append(tfs, TransportIPFamily{tr, af})
+		}
+	}
+
+	found := q.Index.nearest(q.Position, tfs, q.Count, func(r RouterAvail) bool {
+		return q.filter(r) == "" && (q.Load == nil || !q.Load.Saturated(r.Router))
+	})
+	nOK := len(found)
+	if q.Load != nil && len(found) < q.Count {
+		found = append(found, q.Index.nearest(q.Position, tfs, q.Count-len(found), func(r RouterAvail) bool {
+			return q.filter(r) == "" && q.Load.Saturated(r.Router)
+		})...)
+	}
+
+	var penalty float64
+	if len(found) > nOK {
+		// same penalty as ScoreFunc.Rank, which depends on the score range of all candidates
+		var scores []float64
+		for _, r := range avail {
+			if q.filter(r) == "" {
+				scores = append(scores, Distance(q.Position, r.Position()))
+			}
+		}
+		penalty = saturationPenalty(scores)
+	}
+
+	for j, i := range found {
+		sr := scoreDistance(q, avail[i])
+		if j >= nOK {
+			sr.Score += penalty
+		}
+		sr.Transport, _ = q.transportOf(sr.RouterAvail)
+		res.Routers = append(res.Routers, sr)
+	}
+	return res
 }
 
 // Execute executes a query.
-func (q Query) Execute(avail []RouterAvail) (res []RouterAvail) {
+// If fewer than q.Count routers qualify, QueryResult.Shortfall explains why.
+func (q Query) Execute(avail []RouterAvail) (res QueryResult) {
+	rank := q.Rank
+	if GetRanker(rank) == nil {
+		rank = DefaultRank
+	}
+	if rank == RankDistance && q.Client == "" && !q.Explain && len(q.Prefer) == 0 && len(q.Networks) <= 1 &&
+		q.SameCountry != CountryPrefer && q.Index.covers(avail) {
+		res = q.executeIndexed(avail)
+	} else {
+		res = q.executeLinear(avail, GetRanker(rank))
+	}
+
+	if len(res.Routers) < q.Count {
+		res.Shortfall = &QueryShortfall{
+			Transport:  q.Transport,
+			Transports: q.fallbackTransports(),
+			Requested:  q.Count,
+			Returned:   len(res.Routers),
+			Filtered:   map[FilterReason]int{},
+		}
+		for _, router := range avail {
+			if reason := q.filter(router); reason != "" {
+				res.Shortfall.Filtered[reason]++
+			}
+		}
+		for _, reason := range shortfallReasons {
+			if res.Shortfall.Filtered[reason] > 0 {
+				res.Shortfall.Reason = reason
+				break
+			}
+		}
+	}
+	return res
+}
+
+// executeLinear executes a query by ranking all candidates.
+func (q Query) executeLinear(avail []RouterAvail, ranker Ranker) (res QueryResult) {
+	candidates := make([]RouterAvail, 0, len(avail))
+	var filtered []ExplainEntry
 	for _, router := range avail {
-		if q.matchTransport(router) && q.matchNetwork(router) {
-			res = append(res, router)
+		reason := q.filter(router)
+		switch {
+		case reason == "":
+			candidates = append(candidates, router)
+		case q.Explain && reason != FilterPolicy: // routers denied by policy are not disclosed
+			filtered = append(filtered, ExplainEntry{
+				ID:       router.ID(),
+				Country:  router.Country,
+				Filtered: reason,
+				Distance: Distance(q.Position, router.Position()),
+			})
 		}
 	}
-	slices.SortFunc(res, func(a, b RouterAvail) int {
-		return cmp.Compare(Distance(q.Position, a.Position()), Distance(q.Position, b.Position()))
-	})
+	ranked := ranker.Rank(q, candidates)
 
-	if len(res) > q.Count {
-		res = res[:q.Count]
+	if _, ok := ranker.(ScoreRanker); ok && q.Client != "" {
+		ranked = stick(ranked, q.Client)
+	}
+	if q.SameCountry == CountryPrefer {
+		ranked = preferCountry(ranked, q.Country)
+	}
+	if len(q.Networks) > 1 {
+		ranked = prioritizeNetworks(ranked, q)
+	}
+	if len(q.Prefer) > 0 {
+		ranked = prefer(ranked, q.Prefer)
+	}
+	// transport is chosen for returned routers, and for all candidates if explanation is requested
+	res.Routers = ranked[:min(len(ranked), q.Count)]
+	chosen := res.Routers
+	if q.Explain {
+		chosen = ranked
+	}
+	for i := range chosen {
+		chosen[i].Transport, _ = q.transportOf(chosen[i].RouterAvail)
+	}
+	if q.Explain {
+		res.Explain = make([]ExplainEntry, 0, len(ranked)+len(filtered))
+		for i, r := range ranked {
+			res.Explain = append(res.Explain, ExplainEntry{
+				ID:         r.ID(),
+				Country:    r.Country,
+				Transport:  r.Transport,
+				Distance:   Distance(q.Position, r.Position()),
+				Score:      &r.Score,
+				Components: r.Components,
+				Saturated:  q.Load != nil && q.Load.Saturated(r.Router),
+				Rank:       i + 1,
+				Selected:   i < q.Count,
+			})
+		}
+		res.Explain = append(res.Explain, filtered...)
 	}
 	return res
 }
 
+// parseTransports parses a cap parameter value.
+// It may be a single transport, a comma-separated preference-ordered list, or "any".
+func parseTransports(cap string) (list []TransportType) {
+	if cap == "any" {
+		return TransportTypes
+	}
+	for _, tr := range strings.Split(cap, ",") {
+		if tr := TransportType(strings.TrimSpace(tr)); tr != "" && !slices.Contains(list, tr) {
+			list = append(list, tr)
+		}
+	}
+	if len(list) == 0 {
+		list = append(list, TransportUDP)
+	}
+	return list
+}
+
 // ParseQueries constructs a list of Query from URL query string.
 func ParseQueries(qs string) (list []Query) {
 	v, _ := url.ParseQuery(qs)
@@ -61,22 +402,75 @@ func ParseQueries(qs string) (list []Query) {
 	}
 	q.Position[0], _ = strconv.ParseFloat(v.Get("lon"), 64)
 	q.Position[1], _ = strconv.ParseFloat(v.Get("lat"), 64)
-	if network := strings.Trim(v.Get("network"), "/"); network != "" {
-		q.Network = "/" + network + "/"
+	q.Networks, q.ExcludeNetworks = parseNetworks(v["network"])
+	q.Tags = parseTags(v["tag"])
+	q.Country = strings.ToUpper(v.Get("country"))
+	q.SameCountry = parseCountryMode(v.Get("samecountry"))
+	q.Countries = resolveRegions(v["region"])
+	if coord := strings.Split(v.Get("coord"), ","); len(coord) == 3 {
+		c := NewNetCoord()
+		var e0, e1, e2 error
+		c.Vec[0], e0 = strconv.ParseFloat(coord[0], 64)
+		c.Vec[1], e1 = strconv.ParseFloat(coord[1], 64)
+		c.Height, e2 = strconv.ParseFloat(coord[2], 64)
+		if errors.Join(e0, e1, e2) == nil {
+			q.Coord = &c
+		}
+	}
+
+	if diversity, e := strconv.ParseFloat(v.Get("diversity"), 64); e == nil {
+		q.Diversity = min(1, max(0, diversity))
+	}
+
+	if spread, e := strconv.ParseFloat(v.Get("spread"), 64); e == nil && spread > 0 {
+		q.Spread = spread
+	}
+	q.Seed, _ = strconv.ParseInt(v.Get("seed"), 10, 64)
+	q.Client = v.Get("client")
+	q.Exclude, q.Prefer = v["exclude"], v["prefer"]
+	if maxDist, e := strconv.ParseFloat(v.Get("maxdist"), 64); e == nil && maxDist > 0 {
+		q.MaxDistance = maxDist
+	}
+	if maxRTT, e := strconv.ParseFloat(v.Get("maxrtt"), 64); e == nil && maxRTT > 0 {
+		q.MaxRTT = maxRTT
+	}
+	q.Explain = v.Get("explain") == "1"
+
+	q.Rank, q.Base = DefaultRank, RankDistance
+	if rank := RankMode(v.Get("rank")); GetRanker(rank) != nil {
+		q.Rank = rank
+	}
+	if base := RankMode(v.Get("base")); getScoreRanker(base) != nil {
+		q.Base = base
+	}
+	// spread or diversity implies RankRandom or RankDiverse on top of the selected ScoreRanker
+	if q.Rank != RankRandom && q.Rank != RankDiverse && (q.Spread > 0 || q.Diversity > 0) {
+		if getScoreRanker(q.Rank) != nil {
+			q.Base = q.Rank
+		}
+		q.Rank = RankDiverse
+		if q.Spread > 0 {
+			q.Rank = RankRandom
+		}
 	}
 
 	counts := []int{}
 	for _, n := range v["k"] {
 		k, _ := strconv.ParseUint(n, 10, 32)
-		counts = append(counts, max(1, int(k)))
+		if k := max(1, int(k)); MaxCount > 0 {
+			counts = append(counts, min(k, MaxCount))
+		} else {
+			counts = append(counts, k)
+		}
 	}
 	if len(counts) == 0 {
 		counts = append(counts, 1)
 	}
 
-	for i, tr := range v["cap"] {
+	for i, cap := range v["cap"] {
 		q.Count = counts[i%len(counts)]
-		q.Transport = TransportType(tr)
+		q.Transports = parseTransports(cap)
+		q.Transport = q.Transports[0]
 		list = append(list, q)
 	}
 	if len(list) == 0 {
@@ -90,6 +484,12 @@ type QueryResponse struct {
 	Updated int64 `json:"updated"` // last update time, milliseconds since epoch
 
 	Routers []QueryResponseRouter `json:"routers"`
+
+	// Shortfall contains queries for which fewer than k routers qualified.
+	Shortfall []QueryShortfall `json:"shortfall,omitempty"`
+
+	// Explain contains candidate details of each query, if requested with explain=1.
+	Explain []QueryExplanation `json:"explain,omitempty"`
 }
 
 // QueryResponseRouter is part of QueryResponse.
@@ -97,4 +497,9 @@ type QueryResponseRouter struct {
 	Transport TransportType `json:"transport"`
 	Connect   string        `json:"connect"`
 	Prefix    string        `json:"prefix,omitempty"`
+
+	Tags map[string]string `json:"tags,omitempty"`
+
+	Score           float64            `json:"score,omitempty"`
+	ScoreComponents map[string]float64 `json:"scoreComponents,omitempty"`
 }
@@ -1,6 +1,8 @@
 package model_test
 
 import (
+	"fmt"
+	"slices"
 	"testing"
 
 	"github.com/11th-ndn-hackathon/ndn-fch/model"
@@ -25,4 +27,552 @@ func TestQuery(t *testing.T) {
 		assert.InDelta(121.4737, q.Position[0], 0.0001)
 		assert.InDelta(31.2304, q.Position[1], 0.0001)
 	}
+
+	q := model.ParseQueries("k=4294967295&cap=udp")[0]
+	assert.Equal(model.MaxCount, q.Count)
+}
+
+func TestQueryRankRTT(t *testing.T) {
+	assert := assert.New(t)
+
+	udp4 := model.TransportIPFamily{Transport: model.TransportUDP, Family: model.IPv4}
+	avail := []model.RouterAvail{
+		{
+			Router:    testRouter{id: "near-slow", pos: model.LonLat{121.0, 31.0}},
+			Available: map[model.TransportIPFamily]bool{udp4: true},
+			RTT:       map[model.TransportIPFamily]float64{udp4: 300},
+			Loss:      map[model.TransportIPFamily]float64{udp4: 0},
+		},
+		{
+			Router:    testRouter{id: "far-fast", pos: model.LonLat{127.0, 37.5}},
+			Available: map[model.TransportIPFamily]bool{udp4: true},
+			RTT:       map[model.TransportIPFamily]float64{udp4: 30},
+			Loss:      map[model.TransportIPFamily]float64{udp4: 0},
+		},
+	}
+
+	q := model.ParseQueries("k=2&cap=udp&lon=121.4737&lat=31.2304")[0]
+	assert.Equal(model.RankDistance, q.Rank)
+	res := q.Execute(avail).Routers
+	if assert.Len(res, 2) {
+		assert.Equal("near-slow", res[0].ID())
+	}
+
+	q = model.ParseQueries("k=2&cap=udp&lon=121.4737&lat=31.2304&rank=rtt")[0]
+	assert.Equal(model.RankRTT, q.Rank)
+	res = q.Execute(avail).Routers
+	if assert.Len(res, 2) {
+		assert.Equal("far-fast", res[0].ID())
+	}
+
+	avail[1].Loss[udp4] = 0.5
+	res = q.Execute(avail).Routers
+	if assert.Len(res, 2) {
+		assert.Equal("near-slow", res[0].ID())
+	}
+}
+
+func TestQueryDiversity(t *testing.T) {
+	assert := assert.New(t)
+
+	avail := availUDP4([]testRouter{
+		{id: "SH1", pos: model.LonLat{121.47, 31.23}, prefix: "/ndn/sh1", neighbors: map[string]int{"SEL": 1}},
+		{id: "SH2", pos: model.LonLat{121.50, 31.30}, prefix: "/ndn/sh2"},
+		{id: "SEL", pos: model.LonLat{127.00, 37.50}, prefix: "/ndn/sel"},
+		{id: "TYO", pos: model.LonLat{139.70, 35.70}, prefix: "/yoursunny/tyo"},
+	})
+	q := model.ParseQueries("k=3&cap=udp&lon=121.4737&lat=31.2304")[0]
+	assert.Equal([]string{"SH1", "SH2", "SEL"}, ids(q.Execute(avail).Routers))
+
+	q = model.ParseQueries("k=3&cap=udp&lon=121.4737&lat=31.2304&diversity=0.8")[0]
+	assert.InDelta(0.8, q.Diversity, 0.001)
+	assert.Equal([]string{"SH1", "TYO", "SEL"}, ids(q.Execute(avail).Routers))
+}
+
+func TestQuerySpread(t *testing.T) {
+	assert := assert.New(t)
+
+	avail := availUDP4([]testRouter{
+		{id: "A", pos: model.LonLat{121.0, 31.0}},
+		{id: "B", pos: model.LonLat{122.0, 31.0}, capacity: model.Capacity{Weight: 4}},
+		{id: "C", pos: model.LonLat{139.7, 35.7}, capacity: model.Capacity{Weight: 100}},
+	})
+
+	q := model.ParseQueries("k=1&cap=udp&lon=121.4737&lat=31.2304&spread=200&seed=7")[0]
+	assert.InDelta(200, q.Spread, 0.001)
+	assert.EqualValues(7, q.Seed)
+	first := q.Execute(avail).Routers[0].ID()
+	for range 10 {
+		assert.Equal(first, q.Execute(avail).Routers[0].ID(), "same seed should give same answer")
+	}
+
+	counts := map[string]int{}
+	for seed := range int64(1000) {
+		q.Seed = seed + 1
+		res := q.Execute(avail).Routers
+		if assert.Len(res, 1) {
+			counts[res[0].ID()]++
+		}
+	}
+	assert.Zero(counts["C"], "C is outside the band")
+	assert.Greater(counts["B"], counts["A"]*2, "B has higher capacity weight")
+	assert.Greater(counts["A"], 50)
+
+	q = model.ParseQueries("k=3&cap=udp&lon=121.4737&lat=31.2304&spread=200")[0]
+	res := q.Execute(avail).Routers
+	if assert.Len(res, 3) {
+		assert.Equal("C", res[2].ID())
+	}
+}
+
+func TestQuerySticky(t *testing.T) {
+	assert := assert.New(t)
+
+	var routers []testRouter
+	for i := range 8 {
+		routers = append(routers, testRouter{id: fmt.Sprintf("R%d", i), pos: model.LonLat{121.0 + 0.01*float64(i), 35.0}})
+	}
+	routers = append(routers, testRouter{id: "FAR", pos: model.LonLat{-118.2437, 34.0522}})
+	avail := availUDP4(routers)
+
+	assign := func(avail []model.RouterAvail) map[string]string {
+		m := map[string]string{}
+		for i := range 200 {
+			q := model.ParseQueries(fmt.Sprintf("cap=udp&lon=121.4737&lat=31.2304&client=192.0.2.%d", i))[0]
+			res := q.Execute(avail).Routers
+			if assert.Len(res, 1) {
+				m[q.Client] = res[0].ID()
+			}
+		}
+		return m
+	}
+
+	before := assign(avail)
+	assert.Equal(before, assign(avail), "assignment should be stable")
+	perRouter := map[string]int{}
+	for _, id := range before {
+		perRouter[id]++
+	}
+	assert.Len(perRouter, 8, "clients should be spread over equally good routers")
+
+	after := assign(append(slices.Clone(avail[:3]), avail[4:]...))
+	for client, id := range before {
+		if id != "R3" {
+			assert.Equal(id, after[client], "client %s should not move", client)
+		} else {
+			assert.NotEqual("R3", after[client])
+		}
+	}
+}
+
+type testLoadChecker []string
+
+func (c testLoadChecker) Saturated(r model.Router) bool {
+	return slices.Contains(c, r.ID())
+}
+
+func TestQueryLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	avail := availUDP4([]testRouter{
+		{id: "A", pos: model.LonLat{121.0, 31.0}},
+		{id: "B", pos: model.LonLat{127.0, 37.5}},
+	})
+
+	q := model.ParseQueries("k=2&cap=udp&lon=121.4737&lat=31.2304")[0]
+	q.Load = testLoadChecker{"A"}
+	res := q.Execute(avail).Routers
+	if assert.Len(res, 2) {
+		assert.Equal("B", res[0].ID())
+		assert.Equal("A", res[1].ID())
+		assert.Greater(res[1].Score, res[0].Score)
+	}
+
+	// selection by RankDiverse considers the penalty, and its order is kept
+	q = model.ParseQueries("k=2&cap=udp&lon=121.4737&lat=31.2304&rank=diverse&explain=1")[0]
+	q.Load = testLoadChecker{"A"}
+	res = q.Execute(avail).Routers
+	if assert.Len(res, 2) {
+		assert.Equal("B", res[0].ID())
+		assert.Contains(res[1].Components, "saturated")
+	}
+}
+
+// limitedRouter is a testRouter that supports only some TransportIPFamily combinations.
+type limitedRouter struct {
+	testRouter
+	supported []model.TransportIPFamily
+}
+
+func (r limitedRouter) ConnectString(tf model.TransportIPFamily) string {
+	if !slices.Contains(r.supported, tf) {
+		return ""
+	}
+	return r.testRouter.ConnectString(tf)
+}
+
+func TestQueryExplain(t *testing.T) {
+	assert := assert.New(t)
+
+	udp4 := model.TransportIPFamily{Transport: model.TransportUDP, Family: model.IPv4}
+	udp6 := model.TransportIPFamily{Transport: model.TransportUDP, Family: model.IPv6}
+	wss4 := model.TransportIPFamily{Transport: model.TransportWebSocket, Family: model.IPv4}
+	avail := []model.RouterAvail{
+		{
+			Router:    testRouter{id: "FAR", pos: model.LonLat{2.35, 48.86}, prefix: "/ndn/fr"},
+			Available: map[model.TransportIPFamily]bool{udp4: true},
+		},
+		{
+			Router:    testRouter{id: "NEAR", pos: model.LonLat{121.0, 31.0}, prefix: "/ndn/cn"},
+			Available: map[model.TransportIPFamily]bool{udp4: true},
+		},
+		{
+			Router:    testRouter{id: "DOWN", pos: model.LonLat{121.0, 31.0}, prefix: "/ndn/cn"},
+			Available: map[model.TransportIPFamily]bool{udp4: false},
+		},
+		{
+			Router:    limitedRouter{testRouter{id: "V6", pos: model.LonLat{121.0, 31.0}}, []model.TransportIPFamily{udp6}},
+			Available: map[model.TransportIPFamily]bool{udp6: true},
+		},
+		{
+			Router:    limitedRouter{testRouter{id: "WSS", pos: model.LonLat{121.0, 31.0}}, []model.TransportIPFamily{wss4}},
+			Available: map[model.TransportIPFamily]bool{wss4: true},
+		},
+		{
+			Router:    testRouter{id: "NET", pos: model.LonLat{121.0, 31.0}, prefix: "/other"},
+			Available: map[model.TransportIPFamily]bool{udp4: true},
+		},
+	}
+
+	q := model.ParseQueries("k=1&cap=udp&ipv6=0&network=/ndn&lon=121.4737&lat=31.2304")[0]
+	assert.False(q.Explain)
+	res := q.Execute(avail)
+	assert.Nil(res.Explain)
+
+	q = model.ParseQueries("k=1&cap=udp&ipv6=0&network=/ndn&lon=121.4737&lat=31.2304&explain=1")[0]
+	assert.True(q.Explain)
+	res = q.Execute(avail)
+	if assert.Len(res.Routers, 1) {
+		assert.Equal("NEAR", res.Routers[0].ID())
+	}
+
+	entries := map[string]model.ExplainEntry{}
+	for _, entry := range res.Explain {
+		entries[entry.ID] = entry
+	}
+	assert.Len(entries, len(avail))
+
+	assert.Equal(1, entries["NEAR"].Rank)
+	assert.True(entries["NEAR"].Selected)
+	assert.Empty(entries["NEAR"].Filtered)
+	if assert.NotNil(entries["NEAR"].Score) {
+		assert.InDelta(entries["NEAR"].Distance, *entries["NEAR"].Score, 0.001)
+	}
+
+	assert.Equal(2, entries["FAR"].Rank)
+	assert.False(entries["FAR"].Selected)
+	assert.Greater(entries["FAR"].Distance, 9000.0)
+
+	assert.Equal(model.FilterAvailability, entries["DOWN"].Filtered)
+	assert.Equal(model.FilterFamily, entries["V6"].Filtered)
+	assert.Equal(model.FilterTransport, entries["WSS"].Filtered)
+	assert.Equal(model.FilterNetwork, entries["NET"].Filtered)
+	for _, id := range []string{"DOWN", "V6", "WSS", "NET"} {
+		assert.Zero(entries[id].Rank)
+		assert.Nil(entries[id].Score)
+		assert.False(entries[id].Selected)
+	}
+}
+
+func TestQueryMaxDistanceRTT(t *testing.T) {
+	assert := assert.New(t)
+
+	udp4 := model.TransportIPFamily{Transport: model.TransportUDP, Family: model.IPv4}
+	avail := []model.RouterAvail{
+		{
+			Router:    testRouter{id: "SH", pos: model.LonLat{121.0, 31.0}},
+			Available: map[model.TransportIPFamily]bool{udp4: true},
+			RTT:       map[model.TransportIPFamily]float64{udp4: 80},
+		},
+		{
+			Router:    testRouter{id: "SEL", pos: model.LonLat{127.0, 37.5}},
+			Available: map[model.TransportIPFamily]bool{udp4: true},
+		},
+		{
+			Router:    testRouter{id: "PAR", pos: model.LonLat{2.35, 48.86}},
+			Available: map[model.TransportIPFamily]bool{udp4: true},
+		},
+	}
+
+	q := model.ParseQueries("k=3&cap=udp&ipv6=0&lon=121.4737&lat=31.2304")[0]
+	res := q.Execute(avail)
+	assert.Len(res.Routers, 3)
+	assert.Nil(res.Shortfall)
+
+	q = model.ParseQueries("k=3&cap=udp&ipv6=0&lon=121.4737&lat=31.2304&maxdist=2000")[0]
+	assert.Equal(2000.0, q.MaxDistance)
+	res = q.Execute(avail)
+	assert.Equal([]string{"SH", "SEL"}, ids(res.Routers))
+	if assert.NotNil(res.Shortfall) {
+		assert.Equal(3, res.Shortfall.Requested)
+		assert.Equal(2, res.Shortfall.Returned)
+		assert.Equal(model.FilterDistance, res.Shortfall.Reason)
+		assert.Equal(map[model.FilterReason]int{model.FilterDistance: 1}, res.Shortfall.Filtered)
+	}
+
+	// SH has probe RTT 80ms, which is measured from the health probe rather than the client and is ignored.
+	// Client RTT is estimated from distance: SH about 1ms, SEL about 9ms, PAR about 90ms.
+	q = model.ParseQueries("k=3&cap=udp&ipv6=0&lon=121.4737&lat=31.2304&maxrtt=50")[0]
+	assert.Equal(50.0, q.MaxRTT)
+	res = q.Execute(avail)
+	assert.Equal([]string{"SH", "SEL"}, ids(res.Routers))
+	if assert.NotNil(res.Shortfall) {
+		assert.Equal(model.FilterRTT, res.Shortfall.Reason)
+		assert.Equal(1, res.Shortfall.Filtered[model.FilterRTT])
+	}
+
+	q.Index = model.NewSpatialIndex(avail)
+	assert.Equal(res, q.Execute(avail))
+
+	// With client coordinate, RTT is predicted from network coordinates where available.
+	far := model.NewNetCoord()
+	far.Vec = [2]float64{60, 0}
+	avail[1].Coord = &far
+	q = model.ParseQueries("k=3&cap=udp&ipv6=0&lon=121.4737&lat=31.2304&maxrtt=50&coord=0,0,0")[0]
+	res = q.Execute(avail)
+	assert.Equal([]string{"SH"}, ids(res.Routers))
+}
+
+func TestQueryExcludePrefer(t *testing.T) {
+	assert := assert.New(t)
+
+	avail := availUDP4([]testRouter{
+		{id: "SH", pos: model.LonLat{121.0, 31.0}},
+		{id: "SEL", pos: model.LonLat{127.0, 37.5}},
+		{id: "TYO", pos: model.LonLat{139.7, 35.7}},
+		{id: "PAR", pos: model.LonLat{2.35, 48.86}},
+	})
+	index := model.NewSpatialIndex(avail)
+	execute := func(qs string) []string {
+		q := model.ParseQueries(qs)[0]
+		q.Index = index
+		return ids(q.Execute(avail).Routers)
+	}
+
+	assert.Equal([]string{"SH", "SEL"}, execute("k=2&cap=udp&lon=121.4737&lat=31.2304"))
+	assert.Equal([]string{"SEL", "TYO"}, execute("k=2&cap=udp&lon=121.4737&lat=31.2304&exclude=SH"))
+	assert.Equal([]string{"TYO", "PAR"}, execute("k=2&cap=udp&lon=121.4737&lat=31.2304&exclude=SH&exclude=sel.example.net"))
+
+	assert.Equal([]string{"PAR", "SH"}, execute("k=2&cap=udp&lon=121.4737&lat=31.2304&prefer=PAR"))
+	assert.Equal([]string{"TYO", "PAR", "SH"}, execute("k=3&cap=udp&lon=121.4737&lat=31.2304&prefer=TYO&prefer=XXX&prefer=PAR"))
+	assert.Equal([]string{"SEL", "TYO"}, execute("k=2&cap=udp&lon=121.4737&lat=31.2304&prefer=SH&exclude=SH"))
+}
+
+func TestQueryTransportFallback(t *testing.T) {
+	assert := assert.New(t)
+
+	list := model.ParseQueries("cap=http3,wss&k=3&cap=any&cap=udp")
+	if assert.Len(list, 3) {
+		assert.Equal(model.TransportH3, list[0].Transport)
+		assert.Equal([]model.TransportType{model.TransportH3, model.TransportWebSocket}, list[0].Transports)
+		assert.Equal(model.TransportTypes, list[1].Transports)
+		assert.Equal([]model.TransportType{model.TransportUDP}, list[2].Transports)
+	}
+
+	udp4 := model.TransportIPFamily{Transport: model.TransportUDP, Family: model.IPv4}
+	wss4 := model.TransportIPFamily{Transport: model.TransportWebSocket, Family: model.IPv4}
+	h34 := model.TransportIPFamily{Transport: model.TransportH3, Family: model.IPv4}
+	avail := []model.RouterAvail{
+		{
+			Router:    testRouter{id: "SH", pos: model.LonLat{121.0, 31.0}},
+			Available: map[model.TransportIPFamily]bool{wss4: true, h34: false},
+		},
+		{
+			Router:    testRouter{id: "SEL", pos: model.LonLat{127.0, 37.5}},
+			Available: map[model.TransportIPFamily]bool{wss4: true, h34: true},
+		},
+		{
+			Router:    testRouter{id: "TYO", pos: model.LonLat{139.7, 35.7}},
+			Available: map[model.TransportIPFamily]bool{udp4: true},
+		},
+	}
+	index := model.NewSpatialIndex(avail)
+
+	for _, useIndex := range []bool{false, true} {
+		q := model.ParseQueries("k=3&cap=http3,wss&ipv6=0&lon=121.4737&lat=31.2304")[0]
+		if useIndex {
+			q.Index = index
+		}
+		res := q.Execute(avail)
+		if assert.Len(res.Routers, 2) {
+			assert.Equal("SH", res.Routers[0].ID())
+			assert.Equal(model.TransportWebSocket, res.Routers[0].Transport)
+			assert.Equal("SEL", res.Routers[1].ID())
+			assert.Equal(model.TransportH3, res.Routers[1].Transport)
+		}
+		if assert.NotNil(res.Shortfall) {
+			assert.Equal(model.FilterAvailability, res.Shortfall.Reason)
+		}
+
+		q = model.ParseQueries("k=3&cap=any&ipv6=0&lon=121.4737&lat=31.2304")[0]
+		if useIndex {
+			q.Index = index
+		}
+		res = q.Execute(avail)
+		assert.Equal([]string{"SH", "SEL", "TYO"}, ids(res.Routers))
+		if assert.Len(res.Routers, 3) {
+			assert.Equal(model.TransportWebSocket, res.Routers[1].Transport, "udp and wss are preferred over http3")
+			assert.Equal(model.TransportUDP, res.Routers[2].Transport)
+		}
+	}
+}
+
+func TestQueryNetworks(t *testing.T) {
+	assert := assert.New(t)
+
+	model.NetworkAliases["campus"] = []string{"/edu/a", "/edu/b/"}
+	defer delete(model.NetworkAliases, "campus")
+
+	q := model.ParseQueries("network=campus&network=ndn,yoursunny&network=!ndn/x&network=*")[0]
+	assert.Equal([][]string{{"/edu/a/", "/edu/b/"}, {"/ndn/", "/yoursunny/"}, {"*"}}, q.Networks)
+	assert.Equal([]string{"/ndn/x/"}, q.ExcludeNetworks)
+
+	avail := availUDP4([]testRouter{
+		{id: "NDN", pos: model.LonLat{121.4, 31.2}, prefix: "/ndn/cn/sh"},
+		{id: "NDNX", pos: model.LonLat{121.0, 31.0}, prefix: "/ndn/x/1"},
+		{id: "OTHER", pos: model.LonLat{120.0, 30.0}, prefix: "/other/1"},
+		{id: "YS", pos: model.LonLat{127.0, 37.5}, prefix: "/yoursunny/sel"},
+		{id: "EDUB", pos: model.LonLat{139.7, 35.7}, prefix: "/edu/b/tyo"},
+		{id: "EDUA", pos: model.LonLat{2.35, 48.86}, prefix: "/edu/a/par"},
+	})
+	index := model.NewSpatialIndex(avail)
+	execute := func(qs string) []string {
+		q := model.ParseQueries(qs)[0]
+		q.Index = index
+		return ids(q.Execute(avail).Routers)
+	}
+
+	assert.Equal([]string{"NDN", "NDNX", "OTHER"}, execute("k=3&cap=udp&lon=121.4737&lat=31.2304"))
+	assert.Equal([]string{"NDN", "NDNX"}, execute("k=3&cap=udp&lon=121.4737&lat=31.2304&network=ndn"))
+	assert.Equal([]string{"NDN", "OTHER", "YS"}, execute("k=3&cap=udp&lon=121.4737&lat=31.2304&network=!ndn/x"))
+	assert.Equal([]string{"EDUB", "EDUA", "NDN", "YS"},
+		execute("k=4&cap=udp&lon=121.4737&lat=31.2304&network=campus&network=ndn,yoursunny&network=!ndn/x"))
+	assert.Equal([]string{"YS", "NDN", "NDNX", "OTHER"},
+		execute("k=4&cap=udp&lon=121.4737&lat=31.2304&network=yoursunny&network=*"))
+}
+
+func TestQueryTags(t *testing.T) {
+	assert := assert.New(t)
+
+	q := model.ParseQueries("tag=operator=a,operator=b&tag=propagation")[0]
+	assert.Equal([][]model.TagMatch{
+		{{Key: "operator", Value: "a"}, {Key: "operator", Value: "b"}},
+		{{Key: "propagation"}},
+	}, q.Tags)
+
+	avail := availUDP4([]testRouter{
+		{id: "A1", pos: model.LonLat{121.4, 31.2}, tags: map[string]string{"operator": "a"}},
+		{id: "A2", pos: model.LonLat{121.0, 31.0}, tags: map[string]string{"operator": "a", "propagation": "1"}},
+		{id: "B1", pos: model.LonLat{127.0, 37.5}, tags: map[string]string{"operator": "b", "propagation": "1"}},
+		{id: "C1", pos: model.LonLat{139.7, 35.7}, tags: map[string]string{"operator": "c", "propagation": "1"}},
+		{id: "N", pos: model.LonLat{2.35, 48.86}},
+	})
+	execute := func(qs string) []string {
+		q := model.ParseQueries(qs)[0]
+		return ids(q.Execute(avail).Routers)
+	}
+
+	assert.Equal([]string{"A1", "A2", "B1", "C1", "N"}, execute("k=9&cap=udp&lon=121.4737&lat=31.2304"))
+	assert.Equal([]string{"A1", "A2"}, execute("k=9&cap=udp&lon=121.4737&lat=31.2304&tag=operator=a"))
+	assert.Equal([]string{"A1", "A2", "B1"}, execute("k=9&cap=udp&lon=121.4737&lat=31.2304&tag=operator=a,operator=b"))
+	assert.Equal([]string{"A2", "B1"}, execute("k=9&cap=udp&lon=121.4737&lat=31.2304&tag=operator=a,operator=b&tag=propagation"))
+	assert.Equal([]string{"A2", "B1", "C1"}, execute("k=9&cap=udp&lon=121.4737&lat=31.2304&tag=propagation=1"))
+
+	q = model.ParseQueries("k=9&cap=udp&lon=121.4737&lat=31.2304&tag=operator=z")[0]
+	res := q.Execute(avail)
+	assert.Empty(res.Routers)
+	if assert.NotNil(res.Shortfall) {
+		assert.Equal(model.FilterTag, res.Shortfall.Reason)
+	}
+}
+
+func TestQueryCountry(t *testing.T) {
+	assert := assert.New(t)
+
+	model.Regions["east-asia"] = []string{"CN", "KR", "JP"}
+	defer delete(model.Regions, "east-asia")
+
+	q := model.ParseQueries("country=kr&samecountry=1&region=east-asia&region=fr,us")[0]
+	assert.Equal("KR", q.Country)
+	assert.Equal(model.CountryPrefer, q.SameCountry)
+	assert.Equal([]string{"CN", "FR", "JP", "KR", "US"}, q.Countries)
+
+	avail := availUDP4([]testRouter{
+		{id: "CN1", pos: model.LonLat{121.4, 31.2}},
+		{id: "KR1", pos: model.LonLat{127.0, 37.5}},
+		{id: "JP1", pos: model.LonLat{139.7, 35.7}},
+		{id: "FR1", pos: model.LonLat{2.35, 48.86}},
+		{id: "X", pos: model.LonLat{125.0, 33.0}},
+	})
+	for i, country := range []string{"CN", "KR", "JP", "FR", ""} {
+		avail[i].Country = country
+	}
+	execute := func(qs string) []string {
+		q := model.ParseQueries(qs)[0]
+		return ids(q.Execute(avail).Routers)
+	}
+
+	assert.Equal([]string{"CN1", "X", "KR1", "JP1", "FR1"}, execute("k=9&cap=udp&lon=121.4737&lat=31.2304"))
+	assert.Equal([]string{"KR1", "CN1", "X", "JP1", "FR1"}, execute("k=9&cap=udp&lon=121.4737&lat=31.2304&country=KR&samecountry=prefer"))
+	assert.Equal([]string{"KR1"}, execute("k=9&cap=udp&lon=121.4737&lat=31.2304&country=KR&samecountry=require"))
+	assert.Equal([]string{"CN1", "KR1", "JP1"}, execute("k=9&cap=udp&lon=121.4737&lat=31.2304&region=east-asia"))
+	assert.Equal([]string{"JP1", "FR1"}, execute("k=9&cap=udp&lon=121.4737&lat=31.2304&region=JP,fr"))
+
+	q = model.ParseQueries("k=9&cap=udp&lon=121.4737&lat=31.2304&samecountry=require")[0]
+	res := q.Execute(avail)
+	assert.Empty(res.Routers)
+	if assert.NotNil(res.Shortfall) {
+		assert.Equal(model.FilterCountry, res.Shortfall.Reason)
+	}
+}
+
+type testAccessPolicy map[string]string
+
+func (p testAccessPolicy) Deny(r model.Router) string {
+	return p[r.ID()]
+}
+
+func (p testAccessPolicy) Key() string {
+	return fmt.Sprint(map[string]string(p))
+}
+
+func TestQueryPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	avail := availUDP4([]testRouter{
+		{id: "A", pos: model.LonLat{121.4, 31.2}},
+		{id: "B", pos: model.LonLat{127.0, 37.5}},
+		{id: "C", pos: model.LonLat{139.7, 35.7}},
+	})
+	index := model.NewSpatialIndex(avail)
+
+	q := model.ParseQueries("k=2&cap=udp&lon=121.4737&lat=31.2304")[0]
+	q.Policy = testAccessPolicy{"A": "partner"}
+	assert.Equal([]string{"B", "C"}, ids(q.Execute(avail).Routers))
+	q.Index = index
+	assert.Equal([]string{"B", "C"}, ids(q.Execute(avail).Routers))
+
+	q = model.ParseQueries("k=3&cap=udp&lon=121.4737&lat=31.2304&explain=1")[0]
+	q.Policy = testAccessPolicy{"A": "partner"}
+	res := q.Execute(avail)
+	assert.Equal([]string{"B", "C"}, ids(res.Routers))
+	// routers denied by policy are counted in shortfall, but not disclosed in explanation
+	if assert.Len(res.Explain, 2) {
+		assert.Equal("B", res.Explain[0].ID)
+		assert.Equal("C", res.Explain[1].ID)
+	}
+	if assert.NotNil(res.Shortfall) {
+		assert.Equal(model.FilterPolicy, res.Shortfall.Reason)
+		assert.Equal(1, res.Shortfall.Filtered[model.FilterPolicy])
+	}
 }
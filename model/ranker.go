@@ -0,0 +1,279 @@
+package model
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"sync"
+)
+
+// RankMode is the name of a registered Ranker.
+type RankMode string
+
+// RankMode values of built-in rankers.
+const (
+	RankDistance RankMode = "distance"
+	RankRTT      RankMode = "rtt"
+	RankVivaldi  RankMode = "vivaldi"
+	RankRandom   RankMode = "random"
+	RankDiverse  RankMode = "diverse"
+)
+
+// DefaultRank is the Ranker used when a query does not select one.
+var DefaultRank = RankDistance
+
+// ScoredRouter is a router with its ranking score; lower is better.
+type ScoredRouter struct {
+	RouterAvail
+	Score float64
+	// Components explains Score, set only if the query requests explanation.
+	Components map[string]float64
+
+	// Transport is the chosen transport among acceptable transports of the query.
+	// It is set by Query.Execute.
+	Transport TransportType
+}
+
+func (s *ScoredRouter) setComponent(key string, value float64) {
+	if s.Components == nil {
+		s.Components = map[string]float64{}
+	}
+	s.Components[key] = value
+}
+
+// Ranker orders candidate routers of a query.
+type Ranker interface {
+	// Rank orders candidates that have passed query filters, best first.
+	Rank(q Query, candidates []RouterAvail) []ScoredRouter
+}
+
+// ScoreRanker is a Ranker that orders candidates by a per-router score.
+type ScoreRanker interface {
+	Ranker
+
+	// Score computes the score of one router, and its score components if q.Explain is set.
+	Score(q Query, r RouterAvail) ScoredRouter
+}
+
+var (
+	rankers     = map[RankMode]Ranker{}
+	rankersLock sync.RWMutex
+)
+
+// RegisterRanker registers a Ranker under a name.
+func RegisterRanker(name RankMode, r Ranker) {
+	rankersLock.Lock()
+	defer rankersLock.Unlock()
+	rankers[name] = r
+}
+
+// GetRanker returns a registered Ranker, or nil if it does not exist.
+func GetRanker(name RankMode) Ranker {
+	rankersLock.RLock()
+	defer rankersLock.RUnlock()
+	return rankers[name]
+}
+
+// RankerNames returns names of registered rankers.
+func RankerNames() (names []RankMode) {
+	rankersLock.RLock()
+	defer rankersLock.RUnlock()
+	for name := range rankers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func getScoreRanker(name RankMode) ScoreRanker {
+	sr, _ := GetRanker(name).(ScoreRanker)
+	return sr
+}
+
+// ScoreFunc implements ScoreRanker with a scoring function.
+type ScoreFunc func(q Query, r RouterAvail) ScoredRouter
+
+var _ ScoreRanker = ScoreFunc(nil)
+
+// Score implements ScoreRanker interface.
+func (f ScoreFunc) Score(q Query, r RouterAvail) ScoredRouter {
+	return f(q, r)
+}
+
+// Rank implements Ranker interface.
+// Saturated routers reported by q.Load are penalized, see penalizeSaturated.
+func (f ScoreFunc) Rank(q Query, candidates []RouterAvail) (scored []ScoredRouter) {
+	unsorted := make([]ScoredRouter, len(candidates))
+	order := make([]int, len(candidates))
+	for i, r := range candidates {
+		unsorted[i], order[i] = f(q, r), i
+	}
+	penalizeSaturated(unsorted, q)
+
+	// sorting indices is faster than moving ScoredRouter structs; ties keep candidate order
+	slices.SortFunc(order, func(a, b int) int {
+		return cmp.Or(cmp.Compare(unsorted[a].Score, unsorted[b].Score), cmp.Compare(a, b))
+	})
+	scored = make([]ScoredRouter, len(order))
+	for i, j := range order {
+		scored[i] = unsorted[j]
+	}
+	return scored
+}
+
+// saturationPenalty returns the score penalty of saturated routers among scored candidates.
+// It exceeds the score range of candidates, so that a saturated router scores worse than every other candidate.
+func saturationPenalty(scores []float64) float64 {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, score := range scores {
+		lo, hi = min(lo, score), max(hi, score)
+	}
+	if lo > hi {
+		return 0
+	}
+	return hi - lo + 1
+}
+
+// penalizeSaturated adds a score penalty to routers reported saturated by q.Load.
+// Since the penalty is part of the score, selection in RankRandom and RankDiverse considers it,
+// and ordering by score places saturated routers after other candidates.
+func penalizeSaturated(scored []ScoredRouter, q Query) {
+	load := q.Load
+	if load == nil {
+		return
+	}
+	scores := make([]float64, len(scored))
+	for i, s := range scored {
+		scores[i] = s.Score
+	}
+	penalty := saturationPenalty(scores)
+	for i := range scored {
+		if load.Saturated(scored[i].Router) {
+			if q.Explain {
+				scored[i].setComponent("saturated", penalty)
+			}
+			scored[i].Score += penalty
+		}
+	}
+}
+
+// RankWeights contains weights of RankRTT ranker.
+// Each score component is expressed in milliseconds:
+//   - Distance is multiplied by geographical distance in units of 100 km, roughly the RTT over fiber.
+//   - RTT is multiplied by smoothed probe RTT in milliseconds.
+//   - Loss is multiplied by smoothed loss rate between 0.0 and 1.0.
+type RankWeights struct {
+	Distance float64
+	RTT      float64
+	Loss     float64
+}
+
+// DefaultRankWeights contains RankRTT weights.
+var DefaultRankWeights = RankWeights{
+	Distance: 1,
+	RTT:      1,
+	Loss:     1000,
+}
+
+func scoreDistance(q Query, r RouterAvail) (sr ScoredRouter) {
+	dist := Distance(q.Position, r.Position())
+	sr = ScoredRouter{RouterAvail: r, Score: dist}
+	if q.Explain {
+		sr.Components = map[string]float64{"distance": dist}
+	}
+	return sr
+}
+
+func scoreRTT(q Query, r RouterAvail) ScoredRouter {
+	w, families := DefaultRankWeights, q.families()
+	tr, _ := q.transportOf(r)
+	dist := Distance(q.Position, r.Position())
+	rtt, ok := r.MinRTT(tr, families...)
+	if !ok {
+		rtt = dist / 100
+	}
+	distC, rttC, lossC := w.Distance*dist/100, w.RTT*rtt, w.Loss*r.MinLoss(tr, families...)
+	sr := ScoredRouter{RouterAvail: r, Score: distC + rttC + lossC}
+	if q.Explain {
+		sr.Components = map[string]float64{"distance": distC, "rtt": rttC, "loss": lossC}
+	}
+	return sr
+}
+
+// vivaldiRanker ranks routers by RTT predicted from network coordinates.
+type vivaldiRanker struct{}
+
+func (vivaldiRanker) Score(q Query, r RouterAvail) ScoredRouter {
+	predicted := Distance(q.Position, r.Position()) / 100
+	if q.Coord != nil && r.Coord != nil {
+		predicted = PredictRTT(*q.Coord, *r.Coord)
+	}
+	sr := ScoredRouter{RouterAvail: r, Score: predicted}
+	if q.Explain {
+		sr.Components = map[string]float64{"predictedRtt": predicted}
+	}
+	return sr
+}
+
+func (vr vivaldiRanker) Rank(q Query, candidates []RouterAvail) []ScoredRouter {
+	if q.Coord == nil {
+		q.Coord = nearestCoord(q.Position, candidates)
+	}
+	return ScoreFunc(vr.Score).Rank(q, candidates)
+}
+
+// nearestCoord returns the network coordinate of the router geographically nearest to a position.
+func nearestCoord(pos LonLat, routers []RouterAvail) *NetCoord {
+	var nearest *NetCoord
+	nearestDist := math.Inf(1)
+	for _, router := range routers {
+		if router.Coord == nil {
+			continue
+		}
+		if dist := Distance(pos, router.Position()); dist < nearestDist {
+			nearest, nearestDist = router.Coord, dist
+		}
+	}
+	return nearest
+}
+
+// baseRanker returns the ScoreRanker named by q.Base, defaulting to RankDistance.
+func baseRanker(q Query) ScoreRanker {
+	if sr := getScoreRanker(q.Base); sr != nil {
+		return sr
+	}
+	return ScoreFunc(scoreDistance)
+}
+
+// randomRanker performs weighted random selection within q.Spread of the best base score.
+type randomRanker struct{}
+
+func (randomRanker) Rank(q Query, candidates []RouterAvail) []ScoredRouter {
+	band := q.Spread
+	if band <= 0 {
+		band = math.Inf(1)
+	}
+	return spread(baseRanker(q).Rank(q, candidates), q.Count, band, q.Seed, q.Explain)
+}
+
+// diverseRanker balances base score against failure diversity, tuned by q.Diversity.
+type diverseRanker struct{}
+
+// DefaultDiversity is the diversity used by RankDiverse when a query does not specify one.
+const DefaultDiversity = 0.5
+
+func (diverseRanker) Rank(q Query, candidates []RouterAvail) []ScoredRouter {
+	diversity := q.Diversity
+	if diversity <= 0 {
+		diversity = DefaultDiversity
+	}
+	return diversify(baseRanker(q).Rank(q, candidates), q.Count, diversity, q.Explain)
+}
+
+func init() {
+	RegisterRanker(RankDistance, ScoreFunc(scoreDistance))
+	RegisterRanker(RankRTT, ScoreFunc(scoreRTT))
+	RegisterRanker(RankVivaldi, vivaldiRanker{})
+	RegisterRanker(RankRandom, randomRanker{})
+	RegisterRanker(RankDiverse, diverseRanker{})
+}
@@ -0,0 +1,67 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRankers(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Subset(model.RankerNames(), []model.RankMode{
+		model.RankDistance, model.RankRTT, model.RankVivaldi, model.RankRandom, model.RankDiverse,
+	})
+
+	udp4 := model.TransportIPFamily{Transport: model.TransportUDP, Family: model.IPv4}
+	r := model.RouterAvail{
+		Router:    testRouter{id: "A", pos: model.LonLat{127.0, 37.5}},
+		Available: map[model.TransportIPFamily]bool{udp4: true},
+		RTT:       map[model.TransportIPFamily]float64{udp4: 30},
+		Loss:      map[model.TransportIPFamily]float64{udp4: 0.01},
+	}
+	q := model.ParseQueries("cap=udp&lon=121.4737&lat=31.2304")[0]
+	assert.Nil(model.GetRanker(model.RankRTT).(model.ScoreRanker).Score(q, r).Components, "components require explain")
+	q.Explain = true
+
+	distance := model.GetRanker(model.RankDistance).(model.ScoreRanker).Score(q, r)
+	assert.InDelta(870, distance.Score, 10)
+	assert.Equal(distance.Score, distance.Components["distance"])
+
+	rtt := model.GetRanker(model.RankRTT).(model.ScoreRanker).Score(q, r)
+	assert.InDelta(distance.Score/100, rtt.Components["distance"], 0.001)
+	assert.InDelta(30, rtt.Components["rtt"], 0.001)
+	assert.InDelta(10, rtt.Components["loss"], 0.001)
+	assert.InDelta(rtt.Components["distance"]+30+10, rtt.Score, 0.001)
+
+	_, isScoreRanker := model.GetRanker(model.RankRandom).(model.ScoreRanker)
+	assert.False(isScoreRanker)
+}
+
+type reverseRanker struct{}
+
+func (reverseRanker) Rank(q model.Query, candidates []model.RouterAvail) (scored []model.ScoredRouter) {
+	for i := len(candidates) - 1; i >= 0; i-- {
+		scored = append(scored, model.ScoredRouter{RouterAvail: candidates[i], Score: float64(len(scored))})
+	}
+	return scored
+}
+
+func TestRegisterRanker(t *testing.T) {
+	assert := assert.New(t)
+	model.RegisterRanker("reverse", reverseRanker{})
+
+	avail := availUDP4([]testRouter{{id: "A"}, {id: "B"}, {id: "C"}})
+
+	q := model.ParseQueries("k=2&cap=udp&rank=reverse")[0]
+	assert.EqualValues("reverse", q.Rank)
+	res := q.Execute(avail).Routers
+	if assert.Len(res, 2) {
+		assert.Equal("C", res[0].ID())
+		assert.Equal("B", res[1].ID())
+	}
+
+	q = model.ParseQueries("cap=udp&rank=nonexistent")[0]
+	assert.Equal(model.DefaultRank, q.Rank)
+}
@@ -22,12 +22,115 @@ type Router interface {
 
 	// Neighbor returns a map of neighbor ID and link cost.
 	Neighbors() map[string]int
+
+	// Tags returns metadata tags, such as operator or hosting type.
+	// TagSource is set by every router list source.
+	Tags() map[string]string
+}
+
+// Well-known tag keys.
+const (
+	TagSource = "source" // router list source
+)
+
+// WrapperRouter is an optional interface of Router that wraps another Router.
+// Optional interfaces that the wrapper does not implement are looked up on the wrapped Router.
+type WrapperRouter interface {
+	Unwrap() Router
+}
+
+// RouterAs finds the first Router in the wrapping chain of r that implements optional interface T.
+func RouterAs[T any](r Router) (t T, ok bool) {
+	for r != nil {
+		if t, ok = r.(T); ok {
+			return t, true
+		}
+		wr, isWrapper := r.(WrapperRouter)
+		if !isWrapper {
+			break
+		}
+		r = wr.Unwrap()
+	}
+	return t, false
+}
+
+// OverriddenRouter is an optional interface of Router that reports locally overridden fields.
+type OverriddenRouter interface {
+	Overridden() []string
+}
+
+// EstimatedRouter is an optional interface of Router whose position may be estimated
+// rather than supplied by the operator.
+type EstimatedRouter interface {
+	PositionEstimated() bool
+}
+
+// Capacity contains operator-assigned capacity settings of a router.
+type Capacity struct {
+	// Weight is relative capacity for weighted random selection; zero means 1.
+	Weight float64 `json:"weight,omitempty"`
+
+	// MaxShare is the maximum fraction of query responses that may include this router; zero means unlimited.
+	MaxShare float64 `json:"maxShare,omitempty"`
+
+	// MaxRate is the maximum number of query responses per minute that may include this router; zero means unlimited.
+	MaxRate float64 `json:"maxRate,omitempty"`
+}
+
+// CapacityRouter is an optional interface of Router that has operator-assigned capacity settings.
+type CapacityRouter interface {
+	Capacity() Capacity
+}
+
+// RouterCapacity returns capacity settings of a router, with defaults filled in.
+func RouterCapacity(r Router) (c Capacity) {
+	if cr, ok := RouterAs[CapacityRouter](r); ok {
+		c = cr.Capacity()
+	}
+	if c.Weight <= 0 {
+		c.Weight = 1
+	}
+	return c
 }
 
 // RouterAvail contains router availability information.
 type RouterAvail struct {
 	Router
 	Available map[TransportIPFamily]bool
+	RTT       map[TransportIPFamily]float64 // smoothed RTT in milliseconds
+	Loss      map[TransportIPFamily]float64 // smoothed loss rate between 0.0 and 1.0
+	Coord     *NetCoord                     // network coordinate, nil if unknown
+	Country   string                        // ISO 3166 alpha-2 country code, empty if unknown
+}
+
+// MinRTT returns the lowest smoothed RTT among the given transport and IP families.
+func (r RouterAvail) MinRTT(tr TransportType, families ...IPFamily) (rtt float64, ok bool) {
+	for _, af := range families {
+		tf := TransportIPFamily{tr, af}
+		if v, has := r.RTT[tf]; has && r.Available[tf] && (!ok || v < rtt) {
+			rtt, ok = v, true
+		}
+	}
+	return
+}
+
+// MinLoss returns the lowest smoothed loss rate among the given transport and IP families.
+func (r RouterAvail) MinLoss(tr TransportType, families ...IPFamily) (loss float64) {
+	loss = 1
+	for _, af := range families {
+		tf := TransportIPFamily{tr, af}
+		if v, has := r.Loss[tf]; has && r.Available[tf] {
+			loss = min(loss, v)
+		}
+	}
+	return
+}
+
+// RouterMeasurement is part of RouterAvail JSON representation.
+type RouterMeasurement struct {
+	TransportIPFamily
+	RTT  float64 `json:"rtt,omitempty"`
+	Loss float64 `json:"loss"`
 }
 
 // CountAvail returns number of available TransportIPFamily combinations.
@@ -45,20 +148,49 @@ func (r RouterAvail) MarshalJSON() (j []byte, e error) {
 	s := struct {
 		ID        string              `json:"id"`
 		Position  LonLat              `json:"position"`
+		Estimated bool                `json:"positionEstimated,omitempty"`
 		Prefix    string              `json:"prefix,omitempty"`
 		Neighbors map[string]int      `json:"neighbors"`
 		Available []TransportIPFamily `json:"available"`
+		Measured  []RouterMeasurement `json:"measured,omitempty"`
+		Coord     *NetCoord           `json:"coord,omitempty"`
+		Country   string              `json:"country,omitempty"`
+
+		Capacity   *Capacity         `json:"capacity,omitempty"`
+		Tags       map[string]string `json:"tags,omitempty"`
+		Overridden []string          `json:"overridden,omitempty"`
 	}{
 		ID:        r.Router.ID(),
 		Position:  r.Router.Position(),
 		Prefix:    r.Router.Prefix(),
 		Neighbors: r.Router.Neighbors(),
 		Available: []TransportIPFamily{},
+		Coord:     r.Coord,
+		Country:   r.Country,
 	}
 	for tf, ok := range r.Available {
 		if ok {
 			s.Available = append(s.Available, tf)
 		}
 	}
+	for tf, loss := range r.Loss {
+		s.Measured = append(s.Measured, RouterMeasurement{
+			TransportIPFamily: tf,
+			RTT:               r.RTT[tf],
+			Loss:              loss,
+		})
+	}
+	if cr, ok := RouterAs[CapacityRouter](r.Router); ok {
+		if c := cr.Capacity(); c != (Capacity{}) {
+			s.Capacity = &c
+		}
+	}
+	s.Tags = r.Router.Tags()
+	if er, ok := RouterAs[EstimatedRouter](r.Router); ok {
+		s.Estimated = er.PositionEstimated()
+	}
+	if or, ok := RouterAs[OverriddenRouter](r.Router); ok {
+		s.Overridden = or.Overridden()
+	}
 	return json.Marshal(s)
 }
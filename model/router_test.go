@@ -0,0 +1,63 @@
+package model_test
+
+import (
+	"fmt"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+)
+
+type testRouter struct {
+	id        string
+	pos       model.LonLat
+	prefix    string
+	neighbors map[string]int
+	capacity  model.Capacity
+	tags      map[string]string
+}
+
+func (r testRouter) ID() string {
+	return r.id
+}
+
+func (r testRouter) Position() model.LonLat {
+	return r.pos
+}
+
+func (r testRouter) Prefix() string {
+	return r.prefix
+}
+
+func (r testRouter) ConnectString(tf model.TransportIPFamily) string {
+	return fmt.Sprintf("%s.example.net:%s", r.id, model.DefaultUDPPort)
+}
+
+func (r testRouter) Neighbors() map[string]int {
+	return r.neighbors
+}
+
+func (r testRouter) Tags() map[string]string {
+	return r.tags
+}
+
+func (r testRouter) Capacity() model.Capacity {
+	return r.capacity
+}
+
+// availUDP4 returns availability entries of routers available over UDP and IPv4.
+func availUDP4(routers []testRouter) (avail []model.RouterAvail) {
+	udp4 := model.TransportIPFamily{Transport: model.TransportUDP, Family: model.IPv4}
+	for _, r := range routers {
+		avail = append(avail, model.RouterAvail{
+			Router:    r,
+			Available: map[model.TransportIPFamily]bool{udp4: true},
+		})
+	}
+	return avail
+}
+
+func ids(res []model.ScoredRouter) (list []string) {
+	for _, r := range res {
+		list = append(list, r.ID())
+	}
+	return list
+}
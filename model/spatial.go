@@ -0,0 +1,146 @@
+package model
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// SpatialIndex answers nearest-router queries over an availability list.
+// It contains a k-d tree on 3D unit vectors for each TransportIPFamily, in which Euclidean
+// (chord) distance is monotonic with great-circle distance.
+type SpatialIndex struct {
+	avail []RouterAvail
+	trees map[TransportIPFamily]kdTree
+}
+
+// NewSpatialIndex builds a SpatialIndex from an availability list.
+// The list must not be modified afterwards.
+func NewSpatialIndex(avail []RouterAvail) *SpatialIndex {
+	points := map[TransportIPFamily][]kdPoint{}
+	for i, r := range avail {
+		v := unitVector(r.Position())
+		for tf, ok := range r.Available {
+			if ok {
+				points[tf] = append(points[tf], kdPoint{v, i})
+			}
+		}
+	}
+
+	idx := &SpatialIndex{
+		avail: avail,
+		trees: map[TransportIPFamily]kdTree{},
+	}
+	for tf, pts := range points {
+		buildKD(pts, 0)
+		idx.trees[tf] = pts
+	}
+	return idx
+}
+
+// covers determines whether the index was built from the same availability list.
+func (idx *SpatialIndex) covers(avail []RouterAvail) bool {
+	return idx != nil && len(idx.avail) == len(avail) && (len(avail) == 0 || &idx.avail[0] == &avail[0])
+}
+
+// nearest returns indices of up to k routers nearest to pos, nearest first.
+// Each router must be available in at least one of tfs and pass the accept function.
+func (idx *SpatialIndex) nearest(pos LonLat, tfs []TransportIPFamily, k int, accept func(r RouterAvail) bool) (found []int) {
+	s := kdSearch{
+		q: unitVector(pos),
+		k: k,
+		accept: func(i int) bool {
+			return accept(idx.avail[i])
+		},
+	}
+	var merged []kdResult
+	for _, tf := range tfs {
+		s.res = nil
+		s.visit(idx.trees[tf], 0)
+		merged = append(merged, s.res...)
+	}
+
+	slices.SortFunc(merged, kdResult.compare)
+	merged = slices.Compact(merged)
+	for _, r := range merged[:min(len(merged), k)] {
+		found = append(found, r.idx)
+	}
+	return found
+}
+
+func unitVector(pos LonLat) [3]float64 {
+	lon, lat := pos[0]*math.Pi/180, pos[1]*math.Pi/180
+	return [3]float64{math.Cos(lat) * math.Cos(lon), math.Cos(lat) * math.Sin(lon), math.Sin(lat)}
+}
+
+type kdPoint struct {
+	v   [3]float64
+	idx int // index into availability list
+}
+
+// kdTree is an implicit balanced k-d tree: the middle element of each slice is the splitting node,
+// preceded by its left subtree and followed by its right subtree.
+type kdTree []kdPoint
+
+func buildKD(pts []kdPoint, depth int) {
+	if len(pts) <= 1 {
+		return
+	}
+	axis, mid := depth%3, len(pts)/2
+	slices.SortFunc(pts, func(a, b kdPoint) int { return cmp.Compare(a.v[axis], b.v[axis]) })
+	buildKD(pts[:mid], depth+1)
+	buildKD(pts[mid+1:], depth+1)
+}
+
+type kdResult struct {
+	d   float64 // squared chord distance
+	idx int
+}
+
+// compare orders by distance, then by position in availability list, matching a stable sort by distance.
+func (a kdResult) compare(b kdResult) int {
+	return cmp.Or(cmp.Compare(a.d, b.d), cmp.Compare(a.idx, b.idx))
+}
+
+type kdSearch struct {
+	q      [3]float64
+	k      int
+	accept func(i int) bool
+	res    []kdResult // sorted, at most k entries
+}
+
+func (s *kdSearch) visit(t kdTree, depth int) {
+	if len(t) == 0 {
+		return
+	}
+	axis, mid := depth%3, len(t)/2
+	p := t[mid]
+	s.offer(p)
+
+	diff := s.q[axis] - p.v[axis]
+	near, far := t[:mid], t[mid+1:]
+	if diff > 0 {
+		near, far = far, near
+	}
+	s.visit(near, depth+1)
+	if len(s.res) < s.k || diff*diff <= s.res[len(s.res)-1].d {
+		s.visit(far, depth+1)
+	}
+}
+
+func (s *kdSearch) offer(p kdPoint) {
+	var d float64
+	for i := range 3 {
+		d += (s.q[i] - p.v[i]) * (s.q[i] - p.v[i])
+	}
+	r := kdResult{d, p.idx}
+	if len(s.res) == s.k && r.compare(s.res[len(s.res)-1]) >= 0 || !s.accept(p.idx) {
+		return
+	}
+
+	i, _ := slices.BinarySearchFunc(s.res, r, kdResult.compare)
+	s.res = slices.Insert(s.res, i, r)
+	if len(s.res) > s.k {
+		s.res = s.res[:s.k]
+	}
+}
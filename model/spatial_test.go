@@ -0,0 +1,115 @@
+package model_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeSyntheticRouters(n int, rng *rand.Rand) (avail []model.RouterAvail) {
+	udp4 := model.TransportIPFamily{Transport: model.TransportUDP, Family: model.IPv4}
+	udp6 := model.TransportIPFamily{Transport: model.TransportUDP, Family: model.IPv6}
+	for i := range n {
+		avail = append(avail, model.RouterAvail{
+			Router: testRouter{
+				id:     fmt.Sprintf("R%d", i),
+				pos:    randomPosition(rng),
+				prefix: fmt.Sprintf("/net%d/R%d", i%3, i),
+			},
+			Available: map[model.TransportIPFamily]bool{
+				udp4: rng.Intn(4) != 0,
+				udp6: rng.Intn(2) != 0,
+			},
+		})
+	}
+	return avail
+}
+
+func randomPosition(rng *rand.Rand) model.LonLat {
+	return model.LonLat{rng.Float64()*360 - 180, rng.Float64()*180 - 90}
+}
+
+func TestSpatialIndex(t *testing.T) {
+	assert := assert.New(t)
+	rng := rand.New(rand.NewSource(1))
+
+	avail := makeSyntheticRouters(2000, rng)
+	index := model.NewSpatialIndex(avail)
+	var saturated testLoadChecker
+	for i := range 50 {
+		saturated = append(saturated, fmt.Sprintf("R%d", i*7))
+	}
+
+	for i := range 200 {
+		q := model.Query{
+			Count:     1 + rng.Intn(8),
+			Transport: model.TransportUDP,
+			IPv4:      i%3 != 1,
+			IPv6:      i%3 != 0,
+			Position:  randomPosition(rng),
+			Rank:      model.RankDistance,
+		}
+		if i%4 == 0 {
+			q.Networks = [][]string{{"/net1/"}}
+		}
+		if i%5 == 0 {
+			q.Load = saturated
+		}
+		if i%10 == 0 {
+			q.Count = 1000
+		}
+
+		linear := q.Execute(avail).Routers
+		q.Index = index
+		indexed := q.Execute(avail).Routers
+		assert.Equal(ids(linear), ids(indexed), "query %d", i)
+		if assert.Equal(len(linear), len(indexed)) {
+			for j := range linear {
+				assert.InDelta(linear[j].Score, indexed[j].Score, 0.001)
+			}
+		}
+	}
+
+	q := model.Query{Count: 3, Transport: model.TransportUDP, IPv4: true, Rank: model.RankDistance, Index: index}
+	assert.Len(q.Execute(avail[:10]).Routers, 3, "index built from another list should not be used")
+	assert.Empty(q.Execute(nil).Routers)
+}
+
+func benchmarkExecute(b *testing.B, useIndex bool) {
+	rng := rand.New(rand.NewSource(1))
+	avail := makeSyntheticRouters(10000, rng)
+	q := model.ParseQueries("k=4&cap=udp")[0]
+	q.Rank = model.RankDistance
+	if useIndex {
+		q.Index = model.NewSpatialIndex(avail)
+	}
+	positions := make([]model.LonLat, 1024)
+	for i := range positions {
+		positions[i] = randomPosition(rng)
+	}
+
+	b.ResetTimer()
+	for i := range b.N {
+		q.Position = positions[i%len(positions)]
+		q.Execute(avail)
+	}
+}
+
+func BenchmarkExecuteLinear(b *testing.B) {
+	benchmarkExecute(b, false)
+}
+
+func BenchmarkExecuteIndex(b *testing.B) {
+	benchmarkExecute(b, true)
+}
+
+func BenchmarkNewSpatialIndex(b *testing.B) {
+	avail := makeSyntheticRouters(10000, rand.New(rand.NewSource(1)))
+	b.ResetTimer()
+	for range b.N {
+		model.NewSpatialIndex(avail)
+	}
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"math/rand"
+	"time"
+)
+
+// spreadMinScore prevents a router at the client position from taking all the weight.
+const spreadMinScore = 1
+
+// spread reorders sorted candidates by weighted random selection without replacement among
+// candidates whose score is within band of the best score.
+// Weight of each candidate is its capacity weight divided by its score.
+// Candidates outside the band keep their order after the selected ones.
+// If explain is set, the weight is recorded as a score component.
+func spread(candidates []ScoredRouter, count int, band float64, seed int64, explain bool) []ScoredRouter {
+	if len(candidates) == 0 {
+		return candidates
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	limit := candidates[0].Score + band
+	n := 0
+	for n < len(candidates) && candidates[n].Score <= limit {
+		n++
+	}
+
+	weights := make([]float64, n)
+	for i := range candidates[:n] {
+		c := &candidates[i]
+		weights[i] = RouterCapacity(c.Router).Weight / max(c.Score, spreadMinScore)
+		if explain {
+			c.setComponent("weight", weights[i])
+		}
+	}
+
+	for picked := 0; picked < min(count, n); picked++ {
+		total := 0.0
+		for _, w := range weights[picked:] {
+			total += w
+		}
+		x, i := rng.Float64()*total, picked
+		for ; i < n-1; i++ {
+			if x -= weights[i]; x < 0 {
+				break
+			}
+		}
+		candidates[picked], candidates[i] = candidates[i], candidates[picked]
+		weights[picked], weights[i] = weights[i], weights[picked]
+	}
+	return candidates
+}
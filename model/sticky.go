@@ -0,0 +1,39 @@
+package model
+
+import (
+	"cmp"
+	"hash/fnv"
+	"slices"
+)
+
+// StickyTolerance is the relative score band within which a client keeps its assigned router.
+// For example, 0.2 means routers scoring up to 20% worse than the best are considered equally good.
+var StickyTolerance = 0.2
+
+func rendezvousHash(client, id string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(client))
+	h.Write([]byte{0})
+	h.Write([]byte(id))
+	return h.Sum64()
+}
+
+// stick reorders sorted candidates by rendezvous hashing of the client key among candidates
+// within StickyTolerance of the best score.
+// Removing a router only reassigns the clients that were assigned to it.
+func stick(candidates []ScoredRouter, client string) []ScoredRouter {
+	if len(candidates) == 0 {
+		return candidates
+	}
+
+	limit := candidates[0].Score * (1 + StickyTolerance)
+	n := 0
+	for n < len(candidates) && candidates[n].Score <= limit {
+		n++
+	}
+
+	slices.SortStableFunc(candidates[:n], func(a, b ScoredRouter) int {
+		return -cmp.Compare(rendezvousHash(client, a.ID()), rendezvousHash(client, b.ID()))
+	})
+	return candidates
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"slices"
+	"strings"
+)
+
+// TagMatch matches a router tag.
+type TagMatch struct {
+	Key string
+	// Value is the expected tag value.
+	// If empty, any non-empty value matches.
+	Value string
+}
+
+func (m TagMatch) match(tags map[string]string) bool {
+	v := tags[m.Key]
+	if m.Value == "" {
+		return v != ""
+	}
+	return v == m.Value
+}
+
+// parseTags parses tag parameter values.
+// Each value is a comma-separated list of "key=value" or "key" alternatives.
+func parseTags(values []string) (clauses [][]TagMatch) {
+	for _, value := range values {
+		var clause []TagMatch
+		for _, alt := range strings.Split(value, ",") {
+			key, value, _ := strings.Cut(alt, "=")
+			if key = strings.TrimSpace(key); key != "" {
+				clause = append(clause, TagMatch{Key: key, Value: strings.TrimSpace(value)})
+			}
+		}
+		if len(clause) > 0 {
+			clauses = append(clauses, clause)
+		}
+	}
+	return clauses
+}
+
+// matchTags determines whether a router satisfies every tag clause.
+func (q Query) matchTags(router Router) bool {
+	if len(q.Tags) == 0 {
+		return true
+	}
+	tags := router.Tags()
+	return !slices.ContainsFunc(q.Tags, func(clause []TagMatch) bool {
+		return !slices.ContainsFunc(clause, func(m TagMatch) bool { return m.match(tags) })
+	})
+}
@@ -3,6 +3,7 @@ package model
 import (
 	"net"
 	"net/url"
+	"slices"
 )
 
 // Default ports.
@@ -72,3 +73,23 @@ var TransportIPFamilies = func() (list []TransportIPFamily) {
 	}
 	return list
 }()
+
+// RouterHosts extracts hostnames from connection strings of a router.
+func RouterHosts(r Router) (hosts []string) {
+	for _, tf := range TransportIPFamilies {
+		connect := r.ConnectString(tf)
+		if connect == "" {
+			continue
+		}
+		var host string
+		if u, e := url.Parse(connect); e == nil && u.Scheme != "" && u.Host != "" {
+			host = u.Hostname()
+		} else {
+			host, _, _ = net.SplitHostPort(connect)
+		}
+		if host != "" && !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,108 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/logging"
+	"go.uber.org/zap"
+)
+
+var logger = logging.New("policy")
+
+// checkInterval is the minimum interval between checking the policy file for changes.
+const checkInterval = time.Second
+
+// File is a policy file that is reloaded when it has changed.
+type File struct {
+	filename string
+
+	lock    sync.Mutex
+	current *Policy
+	modTime time.Time
+	checked time.Time
+}
+
+// NewFile creates File from filename, and loads the policy.
+// Returns nil if filename is empty.
+// Returns an error if the file cannot be loaded, because serving without the policy would
+// disclose restricted routers.
+func NewFile(filename string) (f *File, e error) {
+	if filename == "" {
+		return nil, nil
+	}
+	f = &File{filename: filename, checked: time.Now()}
+	st, e := os.Stat(filename)
+	if e != nil {
+		return nil, e
+	}
+	if e = f.load(st); e != nil {
+		return nil, e
+	}
+	return f, nil
+}
+
+// load loads the policy file, whose stat result is st.
+func (f *File) load(st os.FileInfo) error {
+	p, e := Load(f.filename)
+	if e != nil {
+		return e
+	}
+	p.version = strconv.FormatInt(st.ModTime().UnixNano(), 36)
+	f.current, f.modTime = p, st.ModTime()
+	logger.Info("load success", zap.Int("count", len(p.Rules)))
+	return nil
+}
+
+// Get returns the current policy, reloading the file if it has changed.
+// Returns nil if there is no policy.
+// If the file is removed or cannot be loaded, the previous policy remains in effect.
+func (f *File) Get() *Policy {
+	if f == nil {
+		return nil
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(f.checked) < checkInterval {
+		return f.current
+	}
+	f.checked = now
+
+	st, e := os.Stat(f.filename)
+	switch {
+	case errors.Is(e, fs.ErrNotExist):
+		logger.Error("file removed, keeping previous policy")
+		return f.current
+	case e != nil:
+		logger.Warn("stat error", zap.Error(e))
+		return f.current
+	case st.ModTime().Equal(f.modTime):
+		return f.current
+	}
+
+	if e := f.load(st); e != nil {
+		logger.Error("load error, keeping previous policy", zap.Error(e))
+	}
+	return f.current
+}
+
+// Load reads a policy from a JSON file.
+func Load(filename string) (p *Policy, e error) {
+	body, e := os.ReadFile(filename)
+	if e != nil {
+		return nil, e
+	}
+	p = &Policy{}
+	if e = json.Unmarshal(body, p); e != nil {
+		return nil, e
+	}
+	p.normalize()
+	return p, nil
+}
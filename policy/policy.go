@@ -0,0 +1,139 @@
+// Package policy restricts which routers may be returned to which clients.
+package policy
+
+import (
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+)
+
+// Client identifies the client of a query.
+type Client struct {
+	IP      netip.Addr // invalid if unknown
+	Key     string     // API key, empty if none
+	Country string     // ISO 3166 alpha-2 country code, empty if unknown
+}
+
+// RouterMatch selects routers guarded by a rule.
+// A router matches if its ID is in IDs (when not empty) and it has every tag in Tags.
+// An empty RouterMatch matches every router.
+type RouterMatch struct {
+	IDs  []string          `json:"ids,omitempty"`
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+func (m RouterMatch) match(r model.Router) bool {
+	if len(m.IDs) > 0 && !slices.Contains(m.IDs, r.ID()) {
+		return false
+	}
+	tags := r.Tags()
+	for k, v := range m.Tags {
+		if tags[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ClientMatch selects clients permitted by a rule.
+// A client matches if it satisfies any of the conditions.
+// An empty ClientMatch matches no client.
+type ClientMatch struct {
+	CIDRs     []netip.Prefix `json:"cidrs,omitempty"`
+	Keys      []string       `json:"keys,omitempty"`
+	Countries []string       `json:"countries,omitempty"`
+}
+
+func (m ClientMatch) match(c Client) bool {
+	return c.IP.IsValid() && slices.ContainsFunc(m.CIDRs, func(p netip.Prefix) bool { return p.Contains(c.IP.Unmap()) }) ||
+		c.Key != "" && slices.Contains(m.Keys, c.Key) ||
+		c.Country != "" && slices.Contains(m.Countries, strings.ToUpper(c.Country))
+}
+
+// Rule restricts matching routers to matching clients.
+type Rule struct {
+	Name    string      `json:"name"`
+	Routers RouterMatch `json:"routers"`
+	Clients ClientMatch `json:"clients"`
+}
+
+// Policy is a list of rules.
+//
+// A router guarded by no rule is permitted to every client.
+// A router guarded by one or more rules is permitted to a client that matches any of these rules.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+
+	version string
+}
+
+// normalize assigns default rule names and normalizes country codes.
+func (p *Policy) normalize() {
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.Name == "" {
+			rule.Name = "rule" + strconv.Itoa(i)
+		}
+		for j, c := range rule.Clients.Countries {
+			rule.Clients.Countries[j] = strings.ToUpper(c)
+		}
+		for j, prefix := range rule.Clients.CIDRs {
+			rule.Clients.CIDRs[j] = prefix.Masked()
+		}
+	}
+}
+
+// For evaluates the policy for a client.
+// Returns nil if no router is denied to the client.
+func (p *Policy) For(c Client) model.AccessPolicy {
+	if p == nil {
+		return nil
+	}
+	a := access{version: p.version, granted: map[int]bool{}}
+	for i, rule := range p.Rules {
+		if rule.Clients.match(c) {
+			a.granted[i] = true
+		} else {
+			a.denied = append(a.denied, i)
+		}
+	}
+	if len(a.denied) == 0 {
+		return nil
+	}
+	a.rules = p.Rules
+	return a
+}
+
+// access implements model.AccessPolicy for one client.
+type access struct {
+	version string
+	rules   []Rule
+	granted map[int]bool
+	denied  []int
+}
+
+func (a access) Deny(r model.Router) string {
+	denyRule := ""
+	for i, rule := range a.rules {
+		if !rule.Routers.match(r) {
+			continue
+		}
+		if a.granted[i] {
+			return ""
+		}
+		if denyRule == "" {
+			denyRule = rule.Name
+		}
+	}
+	return denyRule
+}
+
+func (a access) Key() string {
+	granted := slices.Sorted(maps.Keys(a.granted))
+	return fmt.Sprintf("%s:%v", a.version, granted)
+}
@@ -0,0 +1,175 @@
+package policy
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type testRouter struct {
+	id   string
+	tags map[string]string
+}
+
+func (r testRouter) ID() string                                   { return r.id }
+func (r testRouter) Position() model.LonLat                       { return model.LonLat{} }
+func (r testRouter) Prefix() string                               { return "/" + r.id }
+func (r testRouter) ConnectString(model.TransportIPFamily) string { return r.id + ":6363" }
+func (r testRouter) Neighbors() map[string]int                    { return nil }
+func (r testRouter) Tags() map[string]string                      { return r.tags }
+
+const testPolicy = `{
+	"rules": [
+		{
+			"name": "partner",
+			"routers": { "tags": { "operator": "partner" } },
+			"clients": { "cidrs": ["192.0.2.0/24", "2001:db8::/32"], "keys": ["k-partner"] }
+		},
+		{
+			"name": "domestic",
+			"routers": { "ids": ["D1", "D2"] },
+			"clients": { "countries": ["jp"] }
+		},
+		{
+			"routers": { "ids": ["D2"] },
+			"clients": { "keys": ["k-d2"] }
+		},
+		{
+			"name": "disabled",
+			"routers": { "ids": ["X"] }
+		}
+	]
+}`
+
+func TestPolicy(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "policy.json")
+	assert.NoError(t, os.WriteFile(filename, []byte(testPolicy), 0o644))
+	p, e := Load(filename)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, "rule2", p.Rules[2].Name)
+
+	routers := []testRouter{
+		{id: "P1", tags: map[string]string{"operator": "partner"}},
+		{id: "D1"},
+		{id: "D2"},
+		{id: "X"},
+		{id: "O", tags: map[string]string{"operator": "other"}},
+	}
+
+	tests := []struct {
+		name   string
+		client Client
+		denied map[string]string // router ID => rule name
+	}{
+		{
+			name:   "anonymous",
+			client: Client{IP: netip.MustParseAddr("198.51.100.1")},
+			denied: map[string]string{"P1": "partner", "D1": "domestic", "D2": "domestic", "X": "disabled"},
+		},
+		{
+			name:   "partner IPv4",
+			client: Client{IP: netip.MustParseAddr("192.0.2.77")},
+			denied: map[string]string{"D1": "domestic", "D2": "domestic", "X": "disabled"},
+		},
+		{
+			name:   "partner IPv4-mapped",
+			client: Client{IP: netip.MustParseAddr("::ffff:192.0.2.77")},
+			denied: map[string]string{"D1": "domestic", "D2": "domestic", "X": "disabled"},
+		},
+		{
+			name:   "partner IPv6",
+			client: Client{IP: netip.MustParseAddr("2001:db8:1::1")},
+			denied: map[string]string{"D1": "domestic", "D2": "domestic", "X": "disabled"},
+		},
+		{
+			name:   "partner key",
+			client: Client{Key: "k-partner"},
+			denied: map[string]string{"D1": "domestic", "D2": "domestic", "X": "disabled"},
+		},
+		{
+			name:   "country",
+			client: Client{Country: "JP"},
+			denied: map[string]string{"P1": "partner", "X": "disabled"},
+		},
+		{
+			name:   "second rule grants",
+			client: Client{Key: "k-d2"},
+			denied: map[string]string{"P1": "partner", "D1": "domestic", "X": "disabled"},
+		},
+		{
+			name:   "wrong key",
+			client: Client{Key: "k-other", Country: "US"},
+			denied: map[string]string{"P1": "partner", "D1": "domestic", "D2": "domestic", "X": "disabled"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			access := p.For(tt.client)
+			if !assert.NotNil(access) {
+				return
+			}
+			for _, r := range routers {
+				assert.Equal(tt.denied[r.id], access.Deny(r), r.id)
+			}
+		})
+	}
+
+	assert.Equal(t, p.For(Client{Key: "k-partner"}).Key(), p.For(Client{IP: netip.MustParseAddr("192.0.2.1")}).Key())
+	assert.NotEqual(t, p.For(Client{Key: "k-partner"}).Key(), p.For(Client{}).Key())
+
+	var nilPolicy *Policy
+	assert.Nil(t, nilPolicy.For(Client{}))
+	assert.Nil(t, (&Policy{}).For(Client{}))
+}
+
+func TestFile(t *testing.T) {
+	assert := assert.New(t)
+
+	f, e := NewFile("")
+	assert.NoError(e)
+	assert.Nil(f)
+	assert.Nil(f.Get())
+
+	filename := filepath.Join(t.TempDir(), "policy.json")
+	_, e = NewFile(filename)
+	assert.Error(e, "missing file")
+	assert.NoError(os.WriteFile(filename, []byte(`{"rules":`), 0o644))
+	_, e = NewFile(filename)
+	assert.Error(e, "invalid file")
+
+	assert.NoError(os.WriteFile(filename, []byte(testPolicy), 0o644))
+	f, e = NewFile(filename)
+	assert.NoError(e)
+	p := f.Get()
+	if assert.NotNil(p) {
+		assert.Len(p.Rules, 4)
+	}
+	assert.Same(p, f.Get())
+
+	assert.NoError(os.WriteFile(filename, []byte(`{"rules":[`), 0o644))
+	os.Chtimes(filename, time.Now(), time.Now().Add(time.Minute))
+	f.checked = time.Time{}
+	assert.Same(p, f.Get())
+
+	assert.NoError(os.WriteFile(filename, []byte(`{"rules":[{"name":"only"}]}`), 0o644))
+	os.Chtimes(filename, time.Now(), time.Now().Add(2*time.Minute))
+	f.checked = time.Time{}
+	p2 := f.Get()
+	if assert.NotNil(p2) {
+		assert.Equal("only", p2.Rules[0].Name)
+		assert.NotEqual(p.For(Client{}).Key(), p2.For(Client{}).Key())
+	}
+
+	// removed file keeps the last good policy
+	assert.NoError(os.Remove(filename))
+	f.checked = time.Time{}
+	assert.Same(p2, f.Get())
+}
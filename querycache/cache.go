@@ -0,0 +1,154 @@
+// Package querycache caches query results by query parameters and geographical cell.
+package querycache
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+)
+
+// Stats contains cache statistics.
+type Stats struct {
+	Hits    int // answered from cache
+	Misses  int // executed and stored in cache
+	Bypass  int // not cacheable, or cached answer contains a saturated router
+	Entries int // current number of entries
+}
+
+// Cache caches query results.
+//
+// Client position of a cacheable query is snapped to the center of its geohash cell, so that
+// queries within the same cell share a cache entry, and cached and uncached answers are identical.
+// Queries with explanation, sticky assignment, or unseeded random selection are not cacheable.
+type Cache struct {
+	precision  int
+	maxEntries int
+
+	mutex   sync.Mutex
+	updated time.Time
+	entries map[string]model.QueryResult
+	stats   Stats
+}
+
+// New creates a Cache.
+// precision is the geohash length; 5 gives cells of roughly 5 km.
+// maxEntries limits the number of entries; an arbitrary entry is evicted when it is exceeded.
+func New(precision, maxEntries int) *Cache {
+	return &Cache{
+		precision:  max(1, precision),
+		maxEntries: max(1, maxEntries),
+		entries:    map[string]model.QueryResult{},
+	}
+}
+
+func cacheable(q model.Query) bool {
+	return !q.Explain && q.Client == "" && !(q.Rank == model.RankRandom && q.Seed == 0)
+}
+
+// key returns normalized query parameters and geohash cell of a query.
+func (c *Cache) key(q model.Query) (key string, center model.LonLat) {
+	hash, center := geohashCell(q.Position, c.precision)
+	rank := q.Rank
+	if model.GetRanker(rank) == nil {
+		rank = model.DefaultRank
+	}
+	coord := "-"
+	if q.Coord != nil {
+		coord = fmt.Sprintf("%g,%g,%g", q.Coord.Vec[0], q.Coord.Vec[1], q.Coord.Height)
+	}
+	exclude := slices.Clone(q.Exclude)
+	slices.Sort(exclude)
+	policyKey := "-"
+	if q.Policy != nil {
+		policyKey = q.Policy.Key()
+	}
+	return fmt.Sprintf("%s|%d|%s|%q|%t|%t|%q|%q|%q|%s|%s|%q|%q|%g|%g|%q|%q|%s|%s|%g|%g|%d|%s",
+		hash, q.Count, q.Transport, q.Transports, q.IPv4, q.IPv6, q.Networks, q.ExcludeNetworks, q.Tags,
+		q.Country, q.SameCountry, q.Countries, policyKey, q.MaxDistance, q.MaxRTT, exclude, q.Prefer,
+		rank, q.Base, q.Diversity, q.Spread, q.Seed, coord), center
+}
+
+// Execute executes a query, using cached result if available.
+// avail and updated are the availability list and its publish time; a new publish time invalidates the cache.
+// Returns the result and the query that produced it, whose position may be snapped to a cell center.
+func (c *Cache) Execute(q model.Query, avail []model.RouterAvail, updated time.Time) (res model.QueryResult, executed model.Query) {
+	if c == nil {
+		return q.Execute(avail), q
+	}
+	if !cacheable(q) {
+		c.count(&c.stats.Bypass)
+		return q.Execute(avail), q
+	}
+
+	key, center := c.key(q)
+	q.Position = center
+
+	c.mutex.Lock()
+	if !updated.Equal(c.updated) {
+		clear(c.entries)
+		c.updated = updated
+	}
+	res, ok := c.entries[key]
+	c.mutex.Unlock()
+
+	if !ok {
+		// cache entry is computed without load, because load changes between publishes
+		noLoad := q
+		noLoad.Load = nil
+		res = noLoad.Execute(avail)
+		c.store(key, res, updated)
+	}
+
+	// If no cached router is saturated, load deprioritization would not change the answer.
+	if q.Load != nil {
+		for _, r := range res.Routers {
+			if q.Load.Saturated(r.Router) {
+				c.count(&c.stats.Bypass)
+				return q.Execute(avail), q
+			}
+		}
+	}
+
+	if ok {
+		c.count(&c.stats.Hits)
+	} else {
+		c.count(&c.stats.Misses)
+	}
+	return res, q
+}
+
+func (c *Cache) store(key string, res model.QueryResult, updated time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !updated.Equal(c.updated) {
+		return
+	}
+	if len(c.entries) >= c.maxEntries {
+		for k := range c.entries {
+			delete(c.entries, k)
+			break
+		}
+	}
+	c.entries[key] = res
+}
+
+func (c *Cache) count(counter *int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	*counter++
+}
+
+// Stats returns cache statistics.
+func (c *Cache) Stats() (s Stats) {
+	if c == nil {
+		return s
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	s = c.stats
+	s.Entries = len(c.entries)
+	return s
+}
@@ -0,0 +1,102 @@
+package querycache
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type testRouter struct {
+	id  string
+	pos model.LonLat
+}
+
+func (r testRouter) ID() string                                   { return r.id }
+func (r testRouter) Position() model.LonLat                       { return r.pos }
+func (r testRouter) Prefix() string                               { return "/" + r.id }
+func (r testRouter) ConnectString(model.TransportIPFamily) string { return r.id + ":6363" }
+func (r testRouter) Neighbors() map[string]int                    { return nil }
+func (r testRouter) Tags() map[string]string                      { return nil }
+
+type testLoadChecker []string
+
+func (c testLoadChecker) Saturated(r model.Router) bool {
+	return slices.Contains(c, r.ID())
+}
+
+func TestGeohash(t *testing.T) {
+	assert := assert.New(t)
+
+	hash, center := geohashCell(model.LonLat{-5.6, 42.6}, 5)
+	assert.Equal("ezs42", hash)
+	assert.InDelta(-5.603, center[0], 0.001)
+	assert.InDelta(42.605, center[1], 0.001)
+
+	hash, _ = geohashCell(model.LonLat{-5.6, 42.6}, 3)
+	assert.Equal("ezs", hash)
+}
+
+func TestCache(t *testing.T) {
+	assert := assert.New(t)
+
+	udp4 := model.TransportIPFamily{Transport: model.TransportUDP, Family: model.IPv4}
+	avail := []model.RouterAvail{}
+	for _, r := range []testRouter{
+		{id: "A", pos: model.LonLat{121.0, 31.0}},
+		{id: "B", pos: model.LonLat{127.0, 37.5}},
+		{id: "C", pos: model.LonLat{139.7, 35.7}},
+	} {
+		avail = append(avail, model.RouterAvail{
+			Router:    r,
+			Available: map[model.TransportIPFamily]bool{udp4: true},
+		})
+	}
+	updated := time.Unix(1000, 0)
+
+	c := New(5, 100)
+	q := model.ParseQueries("k=2&cap=udp&lon=121.4737&lat=31.2304")[0]
+	res, executed := c.Execute(q, avail, updated)
+	assert.Equal(Stats{Misses: 1, Entries: 1}, c.Stats())
+
+	snapped := q
+	_, snapped.Position = geohashCell(q.Position, 5)
+	assert.Equal(snapped.Position, executed.Position)
+	assert.Equal(snapped.Execute(avail), res, "cached answer should match uncached answer")
+
+	q.Position[0] += 0.001
+	hit, executed := c.Execute(q, avail, updated)
+	assert.Equal(res, hit)
+	assert.Equal(snapped.Position, executed.Position)
+	assert.Equal(Stats{Hits: 1, Misses: 1, Entries: 1}, c.Stats())
+
+	c.Execute(q, avail, updated.Add(time.Minute))
+	assert.Equal(Stats{Hits: 1, Misses: 2, Entries: 1}, c.Stats(), "new list should invalidate cache")
+	updated = updated.Add(time.Minute)
+
+	sticky := q
+	sticky.Client = "192.0.2.0/24"
+	_, executed = c.Execute(sticky, avail, updated)
+	assert.Equal(1, c.Stats().Bypass)
+	assert.Equal(sticky.Position, executed.Position, "uncacheable query should not be snapped")
+
+	q.Load = testLoadChecker{"C"}
+	hit, _ = c.Execute(q, avail, updated)
+	assert.Equal(res.Routers, hit.Routers)
+	assert.Equal(2, c.Stats().Hits)
+
+	q.Load = testLoadChecker{"A"}
+	loaded, _ := c.Execute(q, avail, updated)
+	assert.Equal(2, c.Stats().Bypass)
+	if assert.Len(loaded.Routers, 2) {
+		assert.Equal("B", loaded.Routers[0].ID())
+		assert.Equal("C", loaded.Routers[1].ID())
+	}
+
+	var nilCache *Cache
+	res, _ = nilCache.Execute(q, avail, updated)
+	assert.Len(res.Routers, 2)
+	assert.Zero(nilCache.Stats())
+}
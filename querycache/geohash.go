@@ -0,0 +1,38 @@
+package querycache
+
+import (
+	"strings"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+)
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// geohashCell encodes a position as a geohash of given precision,
+// and returns the center of the geohash cell.
+func geohashCell(pos model.LonLat, precision int) (hash string, center model.LonLat) {
+	lonRange, latRange := [2]float64{-180, 180}, [2]float64{-90, 90}
+	var b strings.Builder
+	even, bits, ch := true, 0, 0
+	for b.Len() < precision {
+		rng, v := &latRange, pos[1]
+		if even {
+			rng, v = &lonRange, pos[0]
+		}
+		mid := (rng[0] + rng[1]) / 2
+		ch <<= 1
+		if v >= mid {
+			ch |= 1
+			rng[0] = mid
+		} else {
+			rng[1] = mid
+		}
+		even = !even
+
+		if bits++; bits == 5 {
+			b.WriteByte(geohashAlphabet[ch])
+			bits, ch = 0, 0
+		}
+	}
+	return b.String(), model.LonLat{(lonRange[0] + lonRange[1]) / 2, (latRange[0] + latRange[1]) / 2}
+}
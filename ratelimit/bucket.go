@@ -0,0 +1,75 @@
+// Package ratelimit provides token bucket rate limiting.
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Bucket is a token bucket.
+// The zero value is unlimited.
+// A Bucket is not safe for concurrent use.
+type Bucket struct {
+	// Rate is the refill rate in tokens per second.
+	Rate float64
+	// Burst is the bucket capacity; zero means unlimited.
+	Burst float64
+
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket creates a full Bucket.
+func NewBucket(rate, burst float64) Bucket {
+	return Bucket{Rate: rate, Burst: burst, tokens: burst}
+}
+
+// refill adds tokens accumulated since last refill.
+func (b *Bucket) refill(now time.Time) {
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens = min(b.Burst, b.tokens+now.Sub(b.last).Seconds()*b.Rate)
+	}
+	b.last = now
+}
+
+// Wait returns how long until n tokens are available, zero if they are available now.
+func (b *Bucket) Wait(now time.Time, n float64) time.Duration {
+	if b.Burst <= 0 {
+		return 0
+	}
+	b.refill(now)
+	lack := n - b.tokens
+	switch {
+	case lack <= 0:
+		return 0
+	case b.Rate <= 0:
+		return math.MaxInt64
+	}
+	return time.Duration(math.Ceil(lack / b.Rate * float64(time.Second)))
+}
+
+// Take removes n tokens if they are available.
+// Otherwise, returns how long until n tokens are available.
+func (b *Bucket) Take(now time.Time, n float64) (retryAfter time.Duration) {
+	if retryAfter = b.Wait(now, n); retryAfter == 0 && b.Burst > 0 {
+		b.tokens -= n
+	}
+	return retryAfter
+}
+
+// Tokens returns the number of available tokens.
+func (b *Bucket) Tokens(now time.Time) float64 {
+	if b.Burst <= 0 {
+		return math.Inf(1)
+	}
+	b.refill(now)
+	return b.tokens
+}
+
+// SetLimit changes rate and capacity, keeping available tokens up to the new capacity.
+func (b *Bucket) SetLimit(rate, burst float64) {
+	if b.Burst <= 0 {
+		b.tokens = burst
+	}
+	b.Rate, b.Burst, b.tokens = rate, burst, min(b.tokens, burst)
+}
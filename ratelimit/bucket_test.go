@@ -0,0 +1,69 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucket(t *testing.T) {
+	assert := assert.New(t)
+	t0 := time.Unix(1600000000, 0)
+
+	var unlimited ratelimit.Bucket
+	for range 100 {
+		assert.Zero(unlimited.Take(t0, 1))
+	}
+
+	b := ratelimit.NewBucket(2, 3)
+	assert.Zero(b.Take(t0, 1))
+	assert.Zero(b.Take(t0, 1))
+	assert.Zero(b.Take(t0, 1))
+	assert.Equal(500*time.Millisecond, b.Take(t0, 1))
+	assert.Equal(250*time.Millisecond, b.Take(t0.Add(250*time.Millisecond), 1))
+	assert.Zero(b.Take(t0.Add(500*time.Millisecond), 1))
+	assert.InDelta(3.0, b.Tokens(t0.Add(time.Hour)), 1e-9)
+
+	b.SetLimit(1, 1)
+	assert.InDelta(1.0, b.Tokens(t0.Add(time.Hour)), 1e-9)
+	assert.Equal(2*time.Second, b.Wait(t0.Add(time.Hour), 3))
+
+	b.SetLimit(0, 0)
+	assert.Zero(b.Take(t0.Add(time.Hour), 1000))
+	b.SetLimit(1, 5)
+	assert.InDelta(5.0, b.Tokens(t0.Add(time.Hour)), 1e-9)
+
+	noRefill := ratelimit.NewBucket(0, 1)
+	assert.Zero(noRefill.Take(t0, 1))
+	assert.Greater(noRefill.Take(t0.Add(time.Hour), 1), 24*time.Hour)
+}
+
+func TestLimiter(t *testing.T) {
+	assert := assert.New(t)
+	t0 := time.Unix(1600000000, 0)
+
+	var disabled *ratelimit.Limiter[string]
+	assert.Nil(ratelimit.NewLimiter[string](0, 10, 10))
+	assert.Zero(disabled.Take("A", t0))
+	assert.Zero(disabled.Stats())
+
+	l := ratelimit.NewLimiter[string](1, 2, 2)
+	assert.Zero(l.Take("A", t0))
+	assert.Zero(l.Take("A", t0))
+	assert.Equal(time.Second, l.Take("A", t0))
+	assert.Zero(l.Take("B", t0))
+	assert.Equal(ratelimit.LimiterStats{Entries: 2, Admitted: 3, Rejected: 1}, l.Stats())
+
+	// B is most recently used, so C evicts A, which gets a full bucket when it appears again
+	assert.Zero(l.Take("C", t0))
+	assert.Equal(2, l.Stats().Entries)
+	assert.Zero(l.Take("A", t0))
+	assert.Zero(l.Take("A", t0))
+	assert.Equal(time.Second, l.Take("A", t0))
+
+	// A evicted B
+	assert.Zero(l.Take("C", t0))
+	assert.Equal(time.Second, l.Take("C", t0))
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LimiterStats contains Limiter statistics.
+type LimiterStats struct {
+	Entries  int // current number of tracked keys
+	Admitted int // admitted requests
+	Rejected int // rejected requests
+}
+
+// Limiter limits requests with a Bucket per key, such as client IP address.
+//
+// State is bounded: when the number of keys exceeds maxEntries, the least recently used key is
+// evicted; it gets a full bucket when it appears again.
+type Limiter[K comparable] struct {
+	rate       float64
+	burst      float64
+	maxEntries int
+
+	mutex   sync.Mutex
+	entries map[K]*list.Element
+	lru     list.List // front is most recently used
+	stats   LimiterStats
+}
+
+type limiterEntry[K comparable] struct {
+	key    K
+	bucket Bucket
+}
+
+// NewLimiter creates a Limiter.
+// rate is in requests per second; burst is the number of requests allowed in a burst.
+// Returns nil if rate or burst is not positive, which admits every request.
+func NewLimiter[K comparable](rate, burst float64, maxEntries int) *Limiter[K] {
+	if rate <= 0 || burst <= 0 {
+		return nil
+	}
+	return &Limiter[K]{
+		rate:       rate,
+		burst:      burst,
+		maxEntries: max(1, maxEntries),
+		entries:    map[K]*list.Element{},
+	}
+}
+
+// Take admits a request of a key if its bucket has a token.
+// Returns zero if admitted, otherwise how long until it would be admitted.
+func (l *Limiter[K]) Take(key K, now time.Time) (retryAfter time.Duration) {
+	if l == nil {
+		return 0
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	elem := l.entries[key]
+	if elem == nil {
+		if l.lru.Len() >= l.maxEntries {
+			oldest := l.lru.Back()
+			delete(l.entries, oldest.Value.(*limiterEntry[K]).key)
+			l.lru.Remove(oldest)
+		}
+		elem = l.lru.PushFront(&limiterEntry[K]{key: key, bucket: NewBucket(l.rate, l.burst)})
+		l.entries[key] = elem
+	} else {
+		l.lru.MoveToFront(elem)
+	}
+
+	if retryAfter = elem.Value.(*limiterEntry[K]).bucket.Take(now, 1); retryAfter > 0 {
+		l.stats.Rejected++
+	} else {
+		l.stats.Admitted++
+	}
+	return retryAfter
+}
+
+// Stats returns limiter statistics.
+func (l *Limiter[K]) Stats() (s LimiterStats) {
+	if l == nil {
+		return s
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	s = l.stats
+	s.Entries = l.lru.Len()
+	return s
+}
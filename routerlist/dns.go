@@ -0,0 +1,248 @@
+package routerlist
+
+import (
+	"context"
+	"maps"
+	"math/rand"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/logging"
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"go.uber.org/zap"
+)
+
+// DNSResolver resolves DNS records for DNS-based router discovery.
+// *net.Resolver implements this interface.
+type DNSResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (cname string, addrs []*net.SRV, e error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+var (
+	dnsLogger  = logging.New("routerlist.dns")
+	dnsDomains = func() []string {
+		if s := os.Getenv("FCH_ROUTERLIST_DNS_DOMAINS"); s != "" {
+			return strings.Split(s, ",")
+		}
+		return []string{}
+	}()
+
+	// Resolver is the DNS resolver used by DNS-based router discovery.
+	Resolver DNSResolver = net.DefaultResolver
+
+	dnsRouters     []model.Router
+	dnsRoutersLock sync.RWMutex
+)
+
+// dnsServices maps SRV service labels to transports.
+var dnsServices = map[string]model.TransportType{
+	"udp": model.TransportUDP,
+	"wss": model.TransportWebSocket,
+}
+
+// dnsRouter is a router discovered from DNS records.
+//
+// Under each configured domain, "_ndn._udp" and "_ndn._wss" SRV records point to router hostnames.
+// TXT records on a router hostname contain one "key=value" attribute each:
+//   - position=lon,lat
+//   - prefix=/ping/server/prefix
+//   - wss-path=/ws/
+//   - tag=key=value
+type dnsRouter struct {
+	host      string
+	domain    string
+	tags      map[string]string
+	position  *model.LonLat
+	prefix    string
+	connect   map[model.TransportType]string
+	hasIPv4   bool
+	hasIPv6   bool
+	neighbors map[string]int
+}
+
+var (
+	_ model.Router     = dnsRouter{}
+	_ positionedRouter = dnsRouter{}
+)
+
+func (r dnsRouter) ID() string {
+	return r.host
+}
+
+func (r dnsRouter) Position() (pos model.LonLat) {
+	if r.position != nil {
+		pos = *r.position
+	}
+	return
+}
+
+func (r dnsRouter) HasPosition() bool {
+	return r.position != nil
+}
+
+func (r dnsRouter) Prefix() string {
+	return r.prefix
+}
+
+func (r dnsRouter) ConnectString(tf model.TransportIPFamily) string {
+	switch tf.Family {
+	case model.IPv4:
+		if !r.hasIPv4 {
+			return ""
+		}
+	case model.IPv6:
+		if !r.hasIPv6 {
+			return ""
+		}
+	}
+	return r.connect[tf.Transport]
+}
+
+func (r dnsRouter) Neighbors() map[string]int {
+	return r.neighbors
+}
+
+func (r dnsRouter) Tags() (tags map[string]string) {
+	tags = maps.Clone(r.tags)
+	tags[model.TagSource] = SourceDNS
+	tags["domain"] = r.domain
+	return tags
+}
+
+// parseTXT applies TXT record attributes.
+func (r *dnsRouter) parseTXT(records []string) {
+	wssPath := "/ws/"
+	for _, record := range records {
+		key, value, _ := strings.Cut(record, "=")
+		switch key {
+		case "position":
+			lon, lat, ok := strings.Cut(value, ",")
+			if !ok {
+				continue
+			}
+			var pos model.LonLat
+			var e0, e1 error
+			pos[0], e0 = strconv.ParseFloat(lon, 64)
+			pos[1], e1 = strconv.ParseFloat(lat, 64)
+			if e0 == nil && e1 == nil {
+				r.position = &pos
+			}
+		case "prefix":
+			r.prefix = value
+		case "wss-path":
+			wssPath = value
+		case "tag":
+			if k, v, ok := strings.Cut(value, "="); ok && k != "" {
+				r.tags[k] = v
+			}
+		}
+	}
+
+	if hostport, ok := r.connect[model.TransportWebSocket]; ok {
+		if host, port, _ := net.SplitHostPort(hostport); port == model.DefaultWebSocketPort {
+			hostport = host
+		}
+		r.connect[model.TransportWebSocket] = (&url.URL{
+			Scheme: "wss",
+			Host:   hostport,
+			Path:   wssPath,
+		}).String()
+	}
+}
+
+// discoverDNSRouters discovers routers under the given domains.
+func discoverDNSRouters(ctx context.Context, resolver DNSResolver, domains []string) (routers []model.Router) {
+	found := map[string]*dnsRouter{}
+	for _, domain := range domains {
+		for service, tr := range dnsServices {
+			_, srvs, e := resolver.LookupSRV(ctx, "ndn", service, domain)
+			if e != nil {
+				dnsLogger.Debug("lookup SRV", zap.String("domain", domain), zap.String("service", service), zap.Error(e))
+				continue
+			}
+			for _, srv := range srvs {
+				host := strings.TrimSuffix(srv.Target, ".")
+				if host == "" { // target "." means the service is not available (RFC 2782)
+					continue
+				}
+				r := found[host]
+				if r == nil {
+					r = &dnsRouter{
+						host:      host,
+						domain:    domain,
+						tags:      map[string]string{},
+						connect:   map[model.TransportType]string{},
+						neighbors: map[string]int{},
+					}
+					found[host] = r
+				}
+				port := strconv.Itoa(int(srv.Port))
+				if srv.Port == 0 {
+					switch tr {
+					case model.TransportUDP:
+						port = model.DefaultUDPPort
+					case model.TransportWebSocket:
+						port = model.DefaultWebSocketPort
+					}
+				}
+				r.connect[tr] = net.JoinHostPort(host, port)
+			}
+		}
+	}
+
+	for host, r := range found {
+		logEntry := dnsLogger.With(zap.String("host", host))
+		txt, e := resolver.LookupTXT(ctx, host)
+		if e != nil {
+			logEntry.Debug("lookup TXT", zap.Error(e))
+		}
+		r.parseTXT(txt)
+
+		addrs, e := resolver.LookupNetIP(ctx, "ip", host)
+		if e != nil {
+			logEntry.Warn("lookup IP", zap.Error(e))
+			continue
+		}
+		for _, ip := range addrs {
+			ip = ip.Unmap()
+			r.hasIPv4 = r.hasIPv4 || ip.Is4()
+			r.hasIPv6 = r.hasIPv6 || ip.Is6()
+		}
+		routers = append(routers, *r)
+	}
+	return routers
+}
+
+func listDNSRouters() []model.Router {
+	dnsRoutersLock.RLock()
+	defer dnsRoutersLock.RUnlock()
+	return slices.Clone(dnsRouters)
+}
+
+func updateDNSRouters() {
+	if len(dnsDomains) == 0 {
+		return
+	}
+	time.AfterFunc(time.Duration(600+rand.Intn(60))*time.Second, updateDNSRouters)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	routers := discoverDNSRouters(ctx, Resolver, dnsDomains)
+
+	dnsRoutersLock.Lock()
+	defer dnsRoutersLock.Unlock()
+	dnsLogger.Debug("update",
+		zap.Int("old-len", len(dnsRouters)),
+		zap.Int("new-len", len(routers)),
+	)
+	dnsRouters = routers
+}
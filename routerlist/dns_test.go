@@ -0,0 +1,177 @@
+package routerlist
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// testDNSServer is an in-process DNS server that answers from a static record set.
+type testDNSServer struct {
+	conn    net.PacketConn
+	records []dnsmessage.Resource
+}
+
+func (s *testDNSServer) serve() {
+	buf := make([]byte, 1500)
+	for {
+		n, addr, e := s.conn.ReadFrom(buf)
+		if e != nil {
+			return
+		}
+
+		var p dnsmessage.Parser
+		hdr, e := p.Start(buf[:n])
+		if e != nil {
+			continue
+		}
+		q, e := p.Question()
+		if e != nil {
+			continue
+		}
+
+		b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: hdr.ID, Response: true, Authoritative: true})
+		b.EnableCompression()
+		b.StartQuestions()
+		b.Question(q)
+		b.StartAnswers()
+		for _, rr := range s.records {
+			if rr.Header.Type != q.Type || !strings.EqualFold(rr.Header.Name.String(), q.Name.String()) {
+				continue
+			}
+			rr.Header.Class = dnsmessage.ClassINET
+			rr.Header.TTL = 60
+			switch body := rr.Body.(type) {
+			case *dnsmessage.SRVResource:
+				b.SRVResource(rr.Header, *body)
+			case *dnsmessage.TXTResource:
+				b.TXTResource(rr.Header, *body)
+			case *dnsmessage.AResource:
+				b.AResource(rr.Header, *body)
+			case *dnsmessage.AAAAResource:
+				b.AAAAResource(rr.Header, *body)
+			}
+		}
+		wire, _ := b.Finish()
+		s.conn.WriteTo(wire, addr)
+	}
+}
+
+func (s *testDNSServer) resolver() *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "udp", s.conn.LocalAddr().String())
+		},
+	}
+}
+
+func rrHeader(name string, typ dnsmessage.Type) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: typ}
+}
+
+func TestDNSRouters(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	conn, e := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(e)
+	defer conn.Close()
+	server := &testDNSServer{
+		conn: conn,
+		records: []dnsmessage.Resource{
+			{Header: rrHeader("_ndn._udp.example.net.", dnsmessage.TypeSRV), Body: &dnsmessage.SRVResource{
+				Port: 6363, Target: dnsmessage.MustNewName("a.example.net."),
+			}},
+			{Header: rrHeader("_ndn._udp.example.net.", dnsmessage.TypeSRV), Body: &dnsmessage.SRVResource{
+				Port: 0, Target: dnsmessage.MustNewName("c.example.net."),
+			}},
+			{Header: rrHeader("_ndn._udp.example.net.", dnsmessage.TypeSRV), Body: &dnsmessage.SRVResource{
+				Port: 6363, Target: dnsmessage.MustNewName("."),
+			}},
+			{Header: rrHeader("_ndn._wss.example.net.", dnsmessage.TypeSRV), Body: &dnsmessage.SRVResource{
+				Port: 443, Target: dnsmessage.MustNewName("a.example.net."),
+			}},
+			{Header: rrHeader("_ndn._wss.example.net.", dnsmessage.TypeSRV), Body: &dnsmessage.SRVResource{
+				Port: 9696, Target: dnsmessage.MustNewName("b.example.net."),
+			}},
+			{Header: rrHeader("a.example.net.", dnsmessage.TypeTXT), Body: &dnsmessage.TXTResource{
+				TXT: []string{"position=121.4737,31.2304"},
+			}},
+			{Header: rrHeader("a.example.net.", dnsmessage.TypeTXT), Body: &dnsmessage.TXTResource{
+				TXT: []string{"prefix=/example/a"},
+			}},
+			{Header: rrHeader("a.example.net.", dnsmessage.TypeTXT), Body: &dnsmessage.TXTResource{
+				TXT: []string{"tag=hosting=cloud"},
+			}},
+			{Header: rrHeader("a.example.net.", dnsmessage.TypeA), Body: &dnsmessage.AResource{
+				A: [4]byte{192, 0, 2, 1},
+			}},
+			{Header: rrHeader("b.example.net.", dnsmessage.TypeTXT), Body: &dnsmessage.TXTResource{
+				TXT: []string{"position=-118.2437,34.0522"},
+			}},
+			{Header: rrHeader("b.example.net.", dnsmessage.TypeTXT), Body: &dnsmessage.TXTResource{
+				TXT: []string{"wss-path=/ndn"},
+			}},
+			{Header: rrHeader("b.example.net.", dnsmessage.TypeAAAA), Body: &dnsmessage.AAAAResource{
+				AAAA: [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1},
+			}},
+			{Header: rrHeader("c.example.net.", dnsmessage.TypeA), Body: &dnsmessage.AResource{
+				A: [4]byte{192, 0, 2, 3},
+			}},
+		},
+	}
+	go server.serve()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	routers := discoverDNSRouters(ctx, server.resolver(), []string{"example.net"})
+	require.Len(routers, 3)
+	slices.SortFunc(routers, func(a, b model.Router) int { return strings.Compare(a.ID(), b.ID()) })
+
+	a, b, c := routers[0], routers[1], routers[2]
+	assert.Equal("a.example.net", a.ID())
+	assert.Equal(model.LonLat{121.4737, 31.2304}, a.Position())
+	assert.Equal("/example/a", a.Prefix())
+	assert.Equal("a.example.net:6363", a.ConnectString(model.TransportIPFamily{Transport: model.TransportUDP, Family: model.IPv4}))
+	assert.Equal("wss://a.example.net/ws/", a.ConnectString(model.TransportIPFamily{Transport: model.TransportWebSocket, Family: model.IPv4}))
+	assert.Equal("", a.ConnectString(model.TransportIPFamily{Transport: model.TransportUDP, Family: model.IPv6}))
+	assert.Equal(map[string]string{"source": "dns", "domain": "example.net", "hosting": "cloud"}, a.Tags())
+
+	assert.Equal("b.example.net", b.ID())
+	assert.Equal("", b.Prefix())
+	assert.Equal("", b.ConnectString(model.TransportIPFamily{Transport: model.TransportUDP, Family: model.IPv6}))
+	assert.Equal("wss://b.example.net:9696/ndn", b.ConnectString(model.TransportIPFamily{Transport: model.TransportWebSocket, Family: model.IPv6}))
+
+	// SRV port 0 means the default port
+	assert.Equal("c.example.net:6363", c.ConnectString(model.TransportIPFamily{Transport: model.TransportUDP, Family: model.IPv4}))
+}
+
+// rootTargetResolver answers every SRV query with target ".", and every host with an address.
+type rootTargetResolver struct{}
+
+func (rootTargetResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	return "", []*net.SRV{{Target: ".", Port: 6363}}, nil
+}
+
+func (rootTargetResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	return nil, nil
+}
+
+func (rootTargetResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	return []netip.Addr{netip.MustParseAddr("192.0.2.1")}, nil
+}
+
+func TestDNSRoutersUnavailable(t *testing.T) {
+	routers := discoverDNSRouters(context.Background(), rootTargetResolver{}, []string{"example.net"})
+	assert.Empty(t, routers, "SRV target . means the service is not available")
+}
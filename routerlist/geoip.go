@@ -0,0 +1,214 @@
+package routerlist
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/logging"
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/caitlinelfring/go-env-default"
+	"github.com/oschwald/maxminddb-golang"
+	"go.uber.org/zap"
+)
+
+var (
+	geoipLogger   = logging.New("routerlist.geoip")
+	geoipFilename = env.GetDefault("FCH_ROUTERLIST_GEOIP_MMDB", "")
+
+	// Geolocation estimates router position when the source does not provide one.
+	// If nil, routers without position are dropped.
+	Geolocation GeoLocator
+
+	// locateConcurrency limits concurrent position estimations in locateRouters.
+	locateConcurrency = 16
+	// locateCacheTTL is how long an estimated position is reused before locating the router again.
+	locateCacheTTL = time.Hour
+	// locateRetryInterval is how long a router that cannot be located is skipped before trying again.
+	locateRetryInterval = 5 * time.Minute
+
+	locateCache     = map[string]locatedPosition{} // by router ID
+	locateCacheLock sync.Mutex
+)
+
+// locatedPosition is a cached result of estimatePosition.
+type locatedPosition struct {
+	hosts   []string
+	pos     model.LonLat
+	ok      bool
+	located time.Time
+}
+
+// valid determines whether the cached result can be used for a router with the given hosts.
+func (lp locatedPosition) valid(hosts []string, now time.Time) bool {
+	ttl := locateCacheTTL
+	if !lp.ok {
+		ttl = locateRetryInterval
+	}
+	return !lp.located.IsZero() && now.Sub(lp.located) < ttl && slices.Equal(lp.hosts, hosts)
+}
+
+// GeoLocator estimates the position of an IP address.
+type GeoLocator interface {
+	Locate(ip netip.Addr) (pos model.LonLat, ok bool)
+}
+
+// positionedRouter is an optional interface of model.Router that may lack position.
+type positionedRouter interface {
+	HasPosition() bool
+}
+
+func hasPosition(r model.Router) bool {
+	pr, ok := r.(positionedRouter)
+	return !ok || pr.HasPosition()
+}
+
+// addrRouter is an optional interface of model.Router that knows its IP addresses.
+type addrRouter interface {
+	Addrs() []netip.Addr
+}
+
+// mmdbGeoLocator implements GeoLocator with a MaxMind DB file in GeoIP2-City format.
+type mmdbGeoLocator struct {
+	db *maxminddb.Reader
+}
+
+func (g mmdbGeoLocator) Locate(ip netip.Addr) (pos model.LonLat, ok bool) {
+	var record struct {
+		Location struct {
+			Latitude  *float64 `maxminddb:"latitude"`
+			Longitude *float64 `maxminddb:"longitude"`
+		} `maxminddb:"location"`
+	}
+	if e := g.db.Lookup(net.IP(ip.AsSlice()), &record); e != nil || record.Location.Latitude == nil || record.Location.Longitude == nil {
+		return pos, false
+	}
+	return model.LonLat{*record.Location.Longitude, *record.Location.Latitude}, true
+}
+
+func openGeoIP() {
+	if geoipFilename == "" || Geolocation != nil {
+		return
+	}
+	db, e := maxminddb.Open(geoipFilename)
+	if e != nil {
+		geoipLogger.Error("open error", zap.Error(e))
+		return
+	}
+	Geolocation = mmdbGeoLocator{db}
+}
+
+// estimatedRouter is a router whose position is estimated from IP geolocation.
+// Other optional interfaces are reached through Unwrap.
+type estimatedRouter struct {
+	model.Router
+	pos model.LonLat
+}
+
+var (
+	_ model.Router          = estimatedRouter{}
+	_ model.WrapperRouter   = estimatedRouter{}
+	_ model.EstimatedRouter = estimatedRouter{}
+)
+
+func (r estimatedRouter) Unwrap() model.Router {
+	return r.Router
+}
+
+func (r estimatedRouter) Position() model.LonLat {
+	return r.pos
+}
+
+func (r estimatedRouter) PositionEstimated() bool {
+	return true
+}
+
+// estimatePosition estimates router position from its IP addresses or hostnames.
+func estimatePosition(ctx context.Context, geo GeoLocator, resolver DNSResolver, r model.Router) (pos model.LonLat, ok bool) {
+	var addrs []netip.Addr
+	if ar, ok := r.(addrRouter); ok {
+		addrs = slices.Clone(ar.Addrs())
+	}
+	for _, host := range model.RouterHosts(r) {
+		if ip, e := netip.ParseAddr(host); e == nil {
+			addrs = append(addrs, ip)
+			continue
+		}
+		resolved, e := resolver.LookupNetIP(ctx, "ip", host)
+		if e != nil {
+			geoipLogger.Debug("lookup IP", zap.String("host", host), zap.Error(e))
+			continue
+		}
+		addrs = append(addrs, resolved...)
+	}
+
+	for _, ip := range addrs {
+		if pos, ok = geo.Locate(ip.Unmap()); ok {
+			return pos, true
+		}
+	}
+	return pos, false
+}
+
+// locateRouters fills in estimated position for routers without position.
+// Routers whose position cannot be determined are dropped.
+// Routers are located concurrently, and results are cached by router ID until the router's hosts change,
+// so that a router is not looked up on every call; routers that are no longer listed are evicted.
+func locateRouters(routers []model.Router) (located []model.Router) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	now := time.Now()
+
+	locateCacheLock.Lock()
+	cache := locateCache
+	locateCacheLock.Unlock()
+
+	positions := make([]locatedPosition, len(routers))
+	sem := make(chan struct{}, locateConcurrency)
+	var wg sync.WaitGroup
+	for i, r := range routers {
+		if hasPosition(r) || Geolocation == nil {
+			continue
+		}
+		hosts := model.RouterHosts(r)
+		if lp := cache[r.ID()]; lp.valid(hosts, now) {
+			positions[i] = lp
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			pos, ok := estimatePosition(ctx, Geolocation, Resolver, r)
+			positions[i] = locatedPosition{hosts: hosts, pos: pos, ok: ok, located: now}
+		}()
+	}
+	wg.Wait()
+
+	cache = map[string]locatedPosition{}
+	for i, r := range routers {
+		if hasPosition(r) {
+			located = append(located, r)
+			continue
+		}
+		if Geolocation == nil {
+			continue
+		}
+		lp := positions[i]
+		cache[r.ID()] = lp
+		if !lp.ok {
+			geoipLogger.Debug("cannot locate", zap.String("id", r.ID()))
+			continue
+		}
+		located = append(located, estimatedRouter{r, lp.pos})
+	}
+
+	locateCacheLock.Lock()
+	locateCache = cache
+	locateCacheLock.Unlock()
+	return located
+}
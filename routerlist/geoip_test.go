@@ -0,0 +1,132 @@
+package routerlist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type testGeoLocator map[netip.Addr]model.LonLat
+
+func (g testGeoLocator) Locate(ip netip.Addr) (pos model.LonLat, ok bool) {
+	pos, ok = g[ip]
+	return
+}
+
+type testHostResolver map[string][]netip.Addr
+
+func (testHostResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	return "", nil, errors.New("not implemented")
+}
+
+func (testHostResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r testHostResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	if addrs, ok := r[host]; ok {
+		return addrs, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func TestEstimatePosition(t *testing.T) {
+	assert := assert.New(t)
+
+	geo := testGeoLocator{
+		netip.MustParseAddr("192.0.2.1"):   {121.4737, 31.2304},
+		netip.MustParseAddr("2001:db8::1"): {-118.2437, 34.0522},
+	}
+	resolver := testHostResolver{
+		"a.example.net": {netip.MustParseAddr("2001:db8::1")},
+	}
+
+	tb := testbedNode{
+		ShortName:   "TB",
+		Site:        "https://tb.example.net/",
+		IPAddresses: []string{"192.0.2.1"},
+	}.Router()
+	if assert.NotNil(tb) {
+		assert.False(hasPosition(*tb))
+		pos, ok := estimatePosition(context.Background(), geo, resolver, *tb)
+		assert.True(ok)
+		assert.Equal(model.LonLat{121.4737, 31.2304}, pos)
+	}
+
+	node := ndn6Node{
+		topo:   &ndn6Topo{HostnameWSS: "wss://%.example.net/ws/"},
+		id:     "a",
+		Public: []string{"wss:6"},
+	}
+	assert.False(hasPosition(node))
+	pos, ok := estimatePosition(context.Background(), geo, resolver, node)
+	assert.True(ok)
+	assert.Equal(model.LonLat{-118.2437, 34.0522}, pos)
+
+	node.id = "b"
+	_, ok = estimatePosition(context.Background(), geo, resolver, node)
+	assert.False(ok)
+
+	j, _ := json.Marshal(model.RouterAvail{Router: estimatedRouter{node, pos}})
+	assert.Contains(string(j), `"positionEstimated":true`)
+
+	// optional interfaces of the wrapped router remain visible
+	patched := estimatedRouter{overlayRouter{node, &overlayEntry{ID: "a", Capacity: &model.Capacity{Weight: 2}}}, pos}
+	assert.Equal(model.Capacity{Weight: 2}, model.RouterCapacity(patched))
+	j, _ = json.Marshal(model.RouterAvail{Router: patched})
+	assert.Contains(string(j), `"overridden":["capacity"]`)
+	assert.Contains(string(j), `"positionEstimated":true`)
+}
+
+// countingResolver is a testHostResolver that counts lookups.
+type countingResolver struct {
+	testHostResolver
+	lookups atomic.Int32
+}
+
+func (r *countingResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	r.lookups.Add(1)
+	return r.testHostResolver.LookupNetIP(ctx, network, host)
+}
+
+func TestLocateRouters(t *testing.T) {
+	assert := assert.New(t)
+	defer func(geo GeoLocator, resolver DNSResolver) {
+		Geolocation, Resolver, locateCache = geo, resolver, map[string]locatedPosition{}
+	}(Geolocation, Resolver)
+
+	Geolocation = testGeoLocator{netip.MustParseAddr("2001:db8::1"): {-118.2437, 34.0522}}
+	resolver := &countingResolver{testHostResolver: testHostResolver{}}
+	Resolver = resolver
+
+	topo := &ndn6Topo{HostnameWSS: "wss://%.example.net/ws/"}
+	var routers []model.Router
+	for _, id := range []string{"a", "b", "c", "d"} {
+		resolver.testHostResolver[id+".example.net"] = []netip.Addr{netip.MustParseAddr("2001:db8::1")}
+		routers = append(routers, ndn6Node{topo: topo, id: id, Public: []string{"wss:6"}})
+	}
+	routers = append(routers, ndn6Node{topo: topo, id: "x", Public: []string{"wss:6"}})
+
+	located := locateRouters(routers)
+	assert.Len(located, 4)
+	assert.EqualValues(5, resolver.lookups.Load())
+
+	// cached, including the failure
+	located = locateRouters(routers)
+	assert.Len(located, 4)
+	assert.EqualValues(5, resolver.lookups.Load())
+
+	// a router with changed hosts is located again, and unlisted routers are evicted
+	routers[0] = ndn6Node{topo: &ndn6Topo{HostnameWSS: "wss://%.example.org/ws/"}, id: "a", Public: []string{"wss:6"}}
+	located = locateRouters(routers[:2])
+	assert.Len(located, 1)
+	assert.EqualValues(6, resolver.lookups.Load())
+	assert.Len(locateCache, 2)
+}
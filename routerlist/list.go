@@ -2,18 +2,48 @@ package routerlist
 
 import "github.com/11th-ndn-hackathon/ndn-fch/model"
 
+// Source names.
+const (
+	SourceTestbed    = "testbed"
+	SourceNDN6       = "ndn6"
+	SourceDNS        = "dns"
+	SourceRegistered = "registered"
+	SourceOverlay    = "overlay"
+)
+
+type source struct {
+	name string
+	list func() []model.Router
+}
+
+var sources = []source{
+	{SourceTestbed, listTestbedRouters},
+	{SourceNDN6, listNDN6Routers},
+	{SourceDNS, listDNSRouters},
+	{SourceRegistered, listRegisteredRouters},
+}
+
 // List returns a list of known routers.
 // Returns a new copy every time, safe to modify.
 func List() (routers []model.Router) {
-	testbedRoutersLock.RLock()
-	defer testbedRoutersLock.RUnlock()
-	routers = append(routers, testbedRouters...)
-	routers = append(routers, ndn6Routers...)
-	return routers
+	ov := loadOverlay()
+	for _, src := range sources {
+		for _, r := range src.list() {
+			if r = ov.apply(src.name, r); r != nil {
+				routers = append(routers, r)
+			}
+		}
+	}
+	routers = append(routers, ov.synthetic()...)
+	return locateRouters(routers)
 }
 
 // Load initializes the list.
 func Load() {
+	openGeoIP()
+	loadOverlay()
+	loadRegisterKeys()
 	loadNDN6Topo()
 	updateTestbedRouters()
+	updateDNSRouters()
 }
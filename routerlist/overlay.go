@@ -0,0 +1,233 @@
+package routerlist
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/netip"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/logging"
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/caitlinelfring/go-env-default"
+	"go.uber.org/zap"
+)
+
+var (
+	overlayLogger   = logging.New("routerlist.overlay")
+	overlayFilename = env.GetDefault("FCH_ROUTERLIST_OVERLAY", "./fch-overlay.json")
+
+	overlayCurrent *overlayFile
+	overlayModTime time.Time
+	overlayLock    sync.Mutex
+)
+
+// overlayFile is a local file that patches routers from any source.
+type overlayFile struct {
+	Routers []*overlayEntry `json:"routers"`
+}
+
+// overlayEntry patches or excludes one router.
+//
+// Source is the router source name, or "*" to match any source.
+// If Source is "overlay", the entry defines a synthetic router.
+type overlayEntry struct {
+	Source    string            `json:"source"`
+	ID        string            `json:"id"`
+	Exclude   bool              `json:"exclude,omitempty"`
+	Position  *model.LonLat     `json:"position,omitempty"`
+	Prefix    *string           `json:"prefix,omitempty"`
+	Connect   map[string]string `json:"connect,omitempty"` // key is "transport:family" or "transport"
+	Neighbors map[string]int    `json:"neighbors,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Capacity  *model.Capacity   `json:"capacity,omitempty"`
+}
+
+func (e *overlayEntry) match(src, id string) bool {
+	return (e.Source == src || e.Source == "*") && e.ID == id
+}
+
+// loadOverlay returns the current overlay, reloading the file if it has changed.
+func loadOverlay() *overlayFile {
+	overlayLock.Lock()
+	defer overlayLock.Unlock()
+
+	st, e := os.Stat(overlayFilename)
+	switch {
+	case errors.Is(e, fs.ErrNotExist):
+		overlayCurrent, overlayModTime = nil, time.Time{}
+		return nil
+	case e != nil:
+		overlayLogger.Warn("stat error", zap.Error(e))
+		return overlayCurrent
+	case st.ModTime().Equal(overlayModTime):
+		return overlayCurrent
+	}
+
+	var ov overlayFile
+	if e := loadJSONFile(overlayFilename, &ov); e != nil {
+		overlayLogger.Error("load error", zap.Error(e))
+		return overlayCurrent
+	}
+	overlayCurrent, overlayModTime = &ov, st.ModTime()
+	overlayLogger.Info("load success", zap.Int("count", len(ov.Routers)))
+	return overlayCurrent
+}
+
+// apply patches a router from the named source.
+// Returns nil if the router is excluded.
+func (ov *overlayFile) apply(src string, r model.Router) model.Router {
+	if ov == nil {
+		return r
+	}
+	for _, e := range ov.Routers {
+		if !e.match(src, r.ID()) {
+			continue
+		}
+		if e.Exclude {
+			return nil
+		}
+		r = overlayRouter{r, e}
+	}
+	return r
+}
+
+// synthetic returns synthetic routers defined in the overlay.
+func (ov *overlayFile) synthetic() (routers []model.Router) {
+	if ov == nil {
+		return nil
+	}
+	for _, e := range ov.Routers {
+		if e.Source == SourceOverlay && !e.Exclude {
+			routers = append(routers, overlayRouter{nil, e})
+		}
+	}
+	return routers
+}
+
+// overlayRouter is a router patched by an overlay entry.
+// Router is nil for a synthetic router.
+type overlayRouter struct {
+	model.Router
+	e *overlayEntry
+}
+
+var (
+	_ model.Router           = overlayRouter{}
+	_ model.OverriddenRouter = overlayRouter{}
+	_ model.CapacityRouter   = overlayRouter{}
+	_ positionedRouter       = overlayRouter{}
+	_ addrRouter             = overlayRouter{}
+)
+
+func (r overlayRouter) ID() string {
+	return r.e.ID
+}
+
+func (r overlayRouter) Position() model.LonLat {
+	switch {
+	case r.e.Position != nil:
+		return *r.e.Position
+	case r.Router == nil:
+		return model.LonLat{}
+	}
+	return r.Router.Position()
+}
+
+func (r overlayRouter) HasPosition() bool {
+	return r.e.Position != nil || (r.Router != nil && hasPosition(r.Router))
+}
+
+func (r overlayRouter) Addrs() []netip.Addr {
+	if ar, ok := r.Router.(addrRouter); ok {
+		return ar.Addrs()
+	}
+	return nil
+}
+
+func (r overlayRouter) Prefix() string {
+	switch {
+	case r.e.Prefix != nil:
+		return *r.e.Prefix
+	case r.Router == nil:
+		return ""
+	}
+	return r.Router.Prefix()
+}
+
+func (r overlayRouter) ConnectString(tf model.TransportIPFamily) string {
+	if connect, ok := r.e.Connect[fmt.Sprintf("%s:%d", tf.Transport, tf.Family)]; ok {
+		return connect
+	}
+	if connect, ok := r.e.Connect[string(tf.Transport)]; ok {
+		return connect
+	}
+	if r.Router == nil {
+		return ""
+	}
+	return r.Router.ConnectString(tf)
+}
+
+func (r overlayRouter) Neighbors() map[string]int {
+	switch {
+	case r.e.Neighbors != nil:
+		return r.e.Neighbors
+	case r.Router == nil:
+		return map[string]int{}
+	}
+	return r.Router.Neighbors()
+}
+
+func (r overlayRouter) Tags() (tags map[string]string) {
+	tags = map[string]string{}
+	if r.Router == nil {
+		tags[model.TagSource] = SourceOverlay
+	} else {
+		maps.Copy(tags, r.Router.Tags())
+	}
+	maps.Copy(tags, r.e.Tags)
+	return tags
+}
+
+func (r overlayRouter) Capacity() model.Capacity {
+	if r.e.Capacity != nil {
+		return *r.e.Capacity
+	}
+	if cr, ok := r.Router.(model.CapacityRouter); ok {
+		return cr.Capacity()
+	}
+	return model.Capacity{}
+}
+
+func (r overlayRouter) Overridden() (fields []string) {
+	if r.Router == nil {
+		return []string{"synthetic"}
+	}
+	if or, ok := r.Router.(model.OverriddenRouter); ok {
+		fields = or.Overridden()
+	}
+	if r.e.Position != nil {
+		fields = append(fields, "position")
+	}
+	if r.e.Prefix != nil {
+		fields = append(fields, "prefix")
+	}
+	if len(r.e.Connect) > 0 {
+		fields = append(fields, "connect")
+	}
+	if r.e.Neighbors != nil {
+		fields = append(fields, "neighbors")
+	}
+	if len(r.e.Tags) > 0 {
+		fields = append(fields, "tags")
+	}
+	if r.e.Capacity != nil {
+		fields = append(fields, "capacity")
+	}
+	slices.Sort(fields)
+	return slices.Compact(fields)
+}
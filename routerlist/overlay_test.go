@@ -0,0 +1,67 @@
+package routerlist
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// untaggedRouter is a router whose Tags returns nil.
+type untaggedRouter struct {
+	ndn6Node
+}
+
+func (untaggedRouter) Tags() map[string]string {
+	return nil
+}
+
+func TestOverlay(t *testing.T) {
+	assert := assert.New(t)
+
+	var ov overlayFile
+	e := json.Unmarshal([]byte(`{
+		"routers": [
+			{ "source": "testbed", "id": "A", "position": [1, 2], "tags": { "operator": "x" } },
+			{ "source": "*", "id": "B", "exclude": true },
+			{ "source": "ndn6", "id": "C", "connect": { "wss:6": "" } },
+			{ "source": "overlay", "id": "S", "prefix": "/s", "connect": { "udp": "192.0.2.1:6363" } }
+		]
+	}`), &ov)
+	assert.NoError(e)
+
+	a := ov.apply(SourceTestbed, ndn6Node{topo: &ndn6Topo{Network: "yoursunny"}, id: "A", PositionV: model.LonLat{3, 4}})
+	assert.Equal(model.LonLat{1, 2}, a.Position())
+	assert.Equal(map[string]string{"source": "ndn6", "network": "yoursunny", "operator": "x"}, a.Tags())
+	assert.Equal([]string{"position", "tags"}, a.(model.OverriddenRouter).Overridden())
+
+	a = ov.apply(SourceNDN6, ndn6Node{id: "A", PositionV: model.LonLat{3, 4}})
+	assert.Equal(model.LonLat{3, 4}, a.Position())
+
+	a = ov.apply(SourceTestbed, untaggedRouter{ndn6Node{id: "A"}})
+	assert.Equal(map[string]string{"operator": "x"}, a.Tags())
+
+	assert.Nil(ov.apply(SourceNDN6, ndn6Node{id: "B"}))
+
+	c := ov.apply(SourceNDN6, ndn6Node{
+		topo:   &ndn6Topo{HostnameWSS: "wss://%.example.net/ws/"},
+		id:     "C",
+		Public: []string{"wss:4", "wss:6"},
+	})
+	assert.Equal("wss://C.example.net/ws/", c.ConnectString(model.TransportIPFamily{Transport: model.TransportWebSocket, Family: model.IPv4}))
+	assert.Equal("", c.ConnectString(model.TransportIPFamily{Transport: model.TransportWebSocket, Family: model.IPv6}))
+
+	synthetic := ov.synthetic()
+	if assert.Len(synthetic, 1) {
+		s := synthetic[0]
+		assert.Equal("S", s.ID())
+		assert.Equal("/s", s.Prefix())
+		assert.Equal("192.0.2.1:6363", s.ConnectString(model.TransportIPFamily{Transport: model.TransportUDP, Family: model.IPv6}))
+		assert.Equal("", s.ConnectString(model.TransportIPFamily{Transport: model.TransportH3, Family: model.IPv4}))
+		assert.Equal(map[string]string{"source": "overlay"}, s.Tags())
+
+		j, _ := json.Marshal(model.RouterAvail{Router: s})
+		assert.Contains(string(j), `"overridden":["synthetic"]`)
+	}
+}
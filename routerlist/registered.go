@@ -0,0 +1,224 @@
+package routerlist
+
+import (
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/logging"
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/caitlinelfring/go-env-default"
+	"go.uber.org/zap"
+)
+
+var (
+	registeredLogger      = logging.New("routerlist.registered")
+	registerKeysFile      = env.GetDefault("FCH_ROUTERLIST_REGISTER_KEYS", "./fch-register-keys.json")
+	registerTTL           = env.GetDurationDefault("FCH_ROUTERLIST_REGISTER_TTL", 15*time.Minute)
+	registerClockSkew     = 5 * time.Minute
+	registerKeysInterval  = time.Second
+	registerKeys          map[string]registerKey
+	registerKeysModTime   time.Time
+	registerKeysChecked   time.Time
+	registeredRouters     = map[string]*registeredRouter{}
+	registeredRoutersLock sync.Mutex
+)
+
+// Registration errors.
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrBadRequest   = errors.New("bad request")
+)
+
+// registerKey contains credentials of a router operator.
+// Either Token or Ed25519 must be set.
+type registerKey struct {
+	Token   string            `json:"token,omitempty"`
+	Ed25519 ed25519.PublicKey `json:"ed25519,omitempty"` // base64
+}
+
+// Registration is a router self-registration or heartbeat request.
+//
+// A heartbeat contains only ID and Timestamp, and extends an existing registration.
+// Any other request is a full registration, which must contain Position and Connect.
+type Registration struct {
+	ID        string            `json:"id"`
+	Timestamp int64             `json:"timestamp"` // milliseconds since epoch
+	Position  *model.LonLat     `json:"position,omitempty"`
+	Prefix    string            `json:"prefix,omitempty"`
+	Connect   map[string]string `json:"connect,omitempty"` // key is "transport:family"
+	Neighbors map[string]int    `json:"neighbors,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+}
+
+func (reg Registration) isHeartbeat() bool {
+	return reg.Position == nil && reg.Prefix == "" && reg.Connect == nil && reg.Neighbors == nil && reg.Tags == nil
+}
+
+// registeredRouter is a router added via self-registration.
+type registeredRouter struct {
+	reg       Registration
+	lastTime  int64
+	expires   time.Time
+	neighbors map[string]int
+}
+
+var _ model.Router = registeredRouter{}
+
+func (r registeredRouter) ID() string {
+	return r.reg.ID
+}
+
+func (r registeredRouter) Position() model.LonLat {
+	return *r.reg.Position
+}
+
+func (r registeredRouter) Prefix() string {
+	return r.reg.Prefix
+}
+
+func (r registeredRouter) ConnectString(tf model.TransportIPFamily) string {
+	return r.reg.Connect[fmt.Sprintf("%s:%d", tf.Transport, tf.Family)]
+}
+
+func (r registeredRouter) Neighbors() map[string]int {
+	return r.neighbors
+}
+
+func (r registeredRouter) Tags() (tags map[string]string) {
+	tags = maps.Clone(r.reg.Tags)
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	tags[model.TagSource] = SourceRegistered
+	return tags
+}
+
+func loadRegisterKeys() {
+	registeredRoutersLock.Lock()
+	defer registeredRoutersLock.Unlock()
+	reloadRegisterKeys(time.Now())
+}
+
+// reloadRegisterKeys reloads the keys file if it has changed since the last load.
+// If the file is removed, all keys are revoked; if it cannot be loaded, the previous keys are kept.
+// Caller must hold registeredRoutersLock.
+func reloadRegisterKeys(now time.Time) {
+	if now.Sub(registerKeysChecked) < registerKeysInterval {
+		return
+	}
+	registerKeysChecked = now
+
+	st, e := os.Stat(registerKeysFile)
+	switch {
+	case errors.Is(e, fs.ErrNotExist):
+		if registerKeys != nil {
+			registeredLogger.Warn("keys file removed, revoking all keys")
+		}
+		registerKeys, registerKeysModTime = nil, time.Time{}
+		return
+	case e != nil:
+		registeredLogger.Warn("stat keys", zap.Error(e))
+		return
+	case st.ModTime().Equal(registerKeysModTime):
+		return
+	}
+
+	var keys map[string]registerKey
+	if e := loadJSONFile(registerKeysFile, &keys); e != nil {
+		registeredLogger.Error("load keys error, keeping previous keys", zap.Error(e))
+		return
+	}
+	registerKeys, registerKeysModTime = keys, st.ModTime()
+	registeredLogger.Info("load keys", zap.Int("count", len(keys)))
+}
+
+// verify checks the authorization header against the router credentials.
+//
+//	Authorization: Bearer <token>
+//	Authorization: Ed25519 <base64 signature over request body>
+func (k registerKey) verify(body []byte, authorization string) bool {
+	scheme, credential, _ := strings.Cut(authorization, " ")
+	switch {
+	case strings.EqualFold(scheme, "Bearer") && k.Token != "":
+		return subtle.ConstantTimeCompare([]byte(credential), []byte(k.Token)) == 1
+	case strings.EqualFold(scheme, "Ed25519") && len(k.Ed25519) == ed25519.PublicKeySize:
+		sig, e := base64.StdEncoding.DecodeString(credential)
+		return e == nil && ed25519.Verify(k.Ed25519, body, sig)
+	}
+	return false
+}
+
+// Register processes a router self-registration or heartbeat request.
+// body is the JSON encoding of Registration; authorization is the HTTP Authorization header.
+//
+// A newly registered router is not served until it has been probed by availlist.
+func Register(body []byte, authorization string) (expires time.Time, e error) {
+	var reg Registration
+	if e := json.Unmarshal(body, &reg); e != nil {
+		return expires, fmt.Errorf("%w: %w", ErrBadRequest, e)
+	}
+
+	now := time.Now()
+	registeredRoutersLock.Lock()
+	defer registeredRoutersLock.Unlock()
+	reloadRegisterKeys(now)
+
+	key, ok := registerKeys[reg.ID]
+	if !ok || !key.verify(body, authorization) {
+		return expires, ErrUnauthorized
+	}
+	if skew := now.Sub(time.UnixMilli(reg.Timestamp)); skew > registerClockSkew || skew < -registerClockSkew {
+		return expires, fmt.Errorf("%w: timestamp out of range", ErrBadRequest)
+	}
+
+	r := registeredRouters[reg.ID]
+	if r != nil && reg.Timestamp <= r.lastTime {
+		return expires, fmt.Errorf("%w: replayed timestamp", ErrUnauthorized)
+	}
+
+	switch {
+	case !reg.isHeartbeat():
+		if len(reg.Connect) == 0 || reg.Position == nil {
+			return expires, fmt.Errorf("%w: missing connect or position", ErrBadRequest)
+		}
+		neighbors := reg.Neighbors
+		if neighbors == nil {
+			neighbors = map[string]int{}
+		}
+		r = &registeredRouter{reg: reg, neighbors: neighbors}
+		registeredRouters[reg.ID] = r
+		registeredLogger.Info("register", zap.String("id", reg.ID))
+	case r == nil || r.expires.Before(now):
+		return expires, fmt.Errorf("%w: heartbeat without registration", ErrBadRequest)
+	}
+
+	r.lastTime = reg.Timestamp
+	r.expires = now.Add(registerTTL)
+	return r.expires, nil
+}
+
+func listRegisteredRouters() (routers []model.Router) {
+	registeredRoutersLock.Lock()
+	defer registeredRoutersLock.Unlock()
+
+	now := time.Now()
+	for id, r := range registeredRouters {
+		if r.expires.Before(now) {
+			registeredLogger.Info("expire", zap.String("id", id))
+			delete(registeredRouters, id)
+			continue
+		}
+		routers = append(routers, *r)
+	}
+	return routers
+}
@@ -0,0 +1,120 @@
+package routerlist
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	assert := assert.New(t)
+
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	writeKeys := func(keys map[string]registerKey) {
+		j, _ := json.Marshal(keys)
+		assert.NoError(os.WriteFile(registerKeysFile, j, 0o644))
+		registerKeysChecked = time.Time{}
+	}
+	defer func(filename string) {
+		registerKeysFile, registerKeys, registerKeysModTime, registerKeysChecked = filename, nil, time.Time{}, time.Time{}
+		registeredRouters = map[string]*registeredRouter{}
+	}(registerKeysFile)
+	registerKeysFile = filepath.Join(t.TempDir(), "register-keys.json")
+	writeKeys(map[string]registerKey{
+		"token-router": {Token: "s3cret"},
+		"key-router":   {Ed25519: pub},
+	})
+	registeredRouters = map[string]*registeredRouter{}
+
+	now := time.Now().UnixMilli()
+	encode := func(reg Registration) []byte {
+		j, _ := json.Marshal(reg)
+		return j
+	}
+	sign := func(body []byte) string {
+		return "Ed25519 " + base64.StdEncoding.EncodeToString(ed25519.Sign(priv, body))
+	}
+
+	full := encode(Registration{
+		ID:        "token-router",
+		Timestamp: now,
+		Position:  &model.LonLat{121.4737, 31.2304},
+		Connect:   map[string]string{"udp:4": "192.0.2.1:6363"},
+	})
+	_, e := Register(full, "Bearer wrong")
+	assert.ErrorIs(e, ErrUnauthorized)
+	_, e = Register(full, "Bearer s3cret")
+	assert.NoError(e)
+	_, e = Register(full, "Bearer s3cret")
+	assert.ErrorIs(e, ErrUnauthorized, "replay")
+
+	heartbeat := encode(Registration{ID: "key-router", Timestamp: now})
+	_, e = Register(heartbeat, sign(heartbeat))
+	assert.ErrorIs(e, ErrBadRequest, "heartbeat before registration")
+
+	full = encode(Registration{
+		ID:        "key-router",
+		Timestamp: now + 1,
+		Position:  &model.LonLat{-118.2437, 34.0522},
+		Connect:   map[string]string{"wss:6": "wss://router.example.net/ws/"},
+	})
+	_, e = Register(full, sign(heartbeat))
+	assert.ErrorIs(e, ErrUnauthorized, "signature over different body")
+	_, e = Register(full, sign(full))
+	assert.NoError(e)
+
+	heartbeat = encode(Registration{ID: "key-router", Timestamp: now + 2})
+	_, e = Register(heartbeat, sign(heartbeat))
+	assert.NoError(e)
+
+	partial := encode(Registration{ID: "key-router", Timestamp: now + 3, Tags: map[string]string{"operator": "x"}})
+	_, e = Register(partial, sign(partial))
+	assert.ErrorIs(e, ErrBadRequest, "registration without connect or position is not a heartbeat")
+
+	stale := encode(Registration{ID: "key-router", Timestamp: now - time.Hour.Milliseconds()})
+	_, e = Register(stale, sign(stale))
+	assert.ErrorIs(e, ErrBadRequest)
+
+	routers := listRegisteredRouters()
+	assert.Len(routers, 2)
+
+	registeredRouters["token-router"].expires = time.Now().Add(-time.Second)
+	routers = listRegisteredRouters()
+	if assert.Len(routers, 1) {
+		r := routers[0]
+		assert.Equal("key-router", r.ID())
+		assert.Equal("wss://router.example.net/ws/", r.ConnectString(model.TransportIPFamily{Transport: model.TransportWebSocket, Family: model.IPv6}))
+		assert.Equal("", r.ConnectString(model.TransportIPFamily{Transport: model.TransportWebSocket, Family: model.IPv4}))
+	}
+
+	// keys file is reloaded when it changes
+	assert.NoError(os.Chtimes(registerKeysFile, time.Time{}, time.Now().Add(-time.Minute)))
+	writeKeys(map[string]registerKey{"token-router": {Token: "n3w"}})
+	heartbeat = encode(Registration{ID: "key-router", Timestamp: now + 4})
+	_, e = Register(heartbeat, sign(heartbeat))
+	assert.ErrorIs(e, ErrUnauthorized, "removed key")
+	heartbeat = encode(Registration{ID: "token-router", Timestamp: now + 5})
+	_, e = Register(heartbeat, "Bearer n3w")
+	assert.ErrorIs(e, ErrBadRequest, "new token accepted, but registration has expired")
+
+	// invalid keys file keeps previous keys
+	assert.NoError(os.WriteFile(registerKeysFile, []byte("{"), 0o644))
+	registerKeysChecked = time.Time{}
+	heartbeat = encode(Registration{ID: "token-router", Timestamp: now + 6})
+	_, e = Register(heartbeat, "Bearer n3w")
+	assert.ErrorIs(e, ErrBadRequest)
+
+	// removed keys file revokes all keys
+	assert.NoError(os.Remove(registerKeysFile))
+	registerKeysChecked = time.Time{}
+	heartbeat = encode(Registration{ID: "token-router", Timestamp: now + 7})
+	_, e = Register(heartbeat, "Bearer n3w")
+	assert.ErrorIs(e, ErrUnauthorized)
+}
@@ -2,7 +2,10 @@ package routerlist
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
+	"io/fs"
+	"maps"
 	"math/rand"
 	"net"
 	"net/http"
@@ -21,10 +24,11 @@ import (
 )
 
 var (
-	testbedLogger    = logging.New("routerlist.testbed")
-	testbedNodesURI  = env.GetDefault("FCH_ROUTERLIST_TESTBED_URI", "https://testbed-status.named-data.net/testbed-nodes.json")
-	testbedNodesFile = env.GetDefault("FCH_ROUTERLIST_TESTBED_NODES", "./fch-testbed-nodes.json")
-	testbedBadList   = func() []string {
+	testbedLogger        = logging.New("routerlist.testbed")
+	testbedNodesURI      = env.GetDefault("FCH_ROUTERLIST_TESTBED_URI", "https://testbed-status.named-data.net/testbed-nodes.json")
+	testbedNodesFile     = env.GetDefault("FCH_ROUTERLIST_TESTBED_NODES", "./fch-testbed-nodes.json")
+	testbedEndpointsFile = env.GetDefault("FCH_ROUTERLIST_TESTBED_ENDPOINTS", "./fch-testbed-endpoints.json")
+	testbedBadList       = func() []string {
 		if s := os.Getenv("FCH_ROUTERLIST_TESTBED_BAD"); s != "" {
 			return strings.Split(s, ",")
 		}
@@ -40,20 +44,35 @@ type testbedRouter struct {
 	host      string
 	hasIPv4   bool
 	hasIPv6   bool
+	addrs     []netip.Addr
 	neighbors map[string]int
 }
 
-var _ model.Router = testbedRouter{}
+var (
+	_ model.Router     = testbedRouter{}
+	_ positionedRouter = testbedRouter{}
+	_ addrRouter       = testbedRouter{}
+)
 
 func (r testbedRouter) ID() string {
 	return r.node.ShortName
 }
 
 func (r testbedRouter) Position() (pos model.LonLat) {
-	pos[1], pos[0] = r.node.Position[0], r.node.Position[1]
+	if r.HasPosition() {
+		pos[1], pos[0] = r.node.Position[0], r.node.Position[1]
+	}
 	return
 }
 
+func (r testbedRouter) HasPosition() bool {
+	return len(r.node.Position) == 2
+}
+
+func (r testbedRouter) Addrs() []netip.Addr {
+	return r.addrs
+}
+
 func (r testbedRouter) Prefix() string {
 	return r.node.Prefix
 }
@@ -70,6 +89,10 @@ func (r testbedRouter) ConnectString(tf model.TransportIPFamily) string {
 		}
 	}
 
+	if connect, ok := r.node.Endpoints[tf.Transport]; ok {
+		return connect
+	}
+
 	switch tf.Transport {
 	case model.TransportUDP:
 		return net.JoinHostPort(r.host, model.DefaultUDPPort)
@@ -87,14 +110,29 @@ func (r testbedRouter) Neighbors() map[string]int {
 	return r.neighbors
 }
 
+func (r testbedRouter) Tags() (tags map[string]string) {
+	tags = map[string]string{}
+	maps.Copy(tags, r.node.Tags)
+	tags[model.TagSource] = SourceTestbed
+	tags["site"] = r.node.Site
+	return tags
+}
+
+// testbedEndpoints contains per-transport endpoint overrides.
+// Each value is a connection string in the format described in model.Router.ConnectString.
+// An empty value disables the transport.
+type testbedEndpoints map[model.TransportType]string
+
 type testbedNode struct {
-	ShortName    string    `json:"shortname"`
-	Site         string    `json:"site"`
-	IPAddresses  []string  `json:"ip_addresses"`
-	Position     []float64 `json:"position"`
-	RealPosition []float64 `json:"_real_position"`
-	Prefix       string    `json:"prefix"`
-	Neighbors    []string  `json:"neighbors"`
+	ShortName    string            `json:"shortname"`
+	Site         string            `json:"site"`
+	IPAddresses  []string          `json:"ip_addresses"`
+	Position     []float64         `json:"position"`
+	RealPosition []float64         `json:"_real_position"`
+	Prefix       string            `json:"prefix"`
+	Neighbors    []string          `json:"neighbors"`
+	Endpoints    testbedEndpoints  `json:"endpoints,omitempty"`
+	Tags         map[string]string `json:"tags,omitempty"`
 }
 
 func (n testbedNode) Router() (r *testbedRouter) {
@@ -116,7 +154,7 @@ func (n testbedNode) Router() (r *testbedRouter) {
 		n.Position = n.RealPosition
 	case len(n.Position) == 2:
 	default:
-		return nil
+		n.Position = nil
 	}
 
 	for _, ipStr := range n.IPAddresses {
@@ -124,6 +162,7 @@ func (n testbedNode) Router() (r *testbedRouter) {
 		if e != nil {
 			continue
 		}
+		r.addrs = append(r.addrs, ip)
 		r.hasIPv4 = r.hasIPv4 || ip.Is4()
 		r.hasIPv6 = r.hasIPv6 || ip.Is6()
 	}
@@ -142,6 +181,29 @@ func (n testbedNode) Router() (r *testbedRouter) {
 	return r
 }
 
+// applyEndpoints merges local endpoint overrides, which take precedence over the feed.
+func (n *testbedNode) applyEndpoints(local testbedEndpoints) {
+	if len(local) == 0 {
+		return
+	}
+	merged := testbedEndpoints{}
+	maps.Copy(merged, n.Endpoints)
+	maps.Copy(merged, local)
+	n.Endpoints = merged
+}
+
+// loadTestbedEndpoints loads local endpoint overrides keyed by shortname.
+func loadTestbedEndpoints() (m map[string]testbedEndpoints) {
+	if _, e := os.Stat(testbedEndpointsFile); errors.Is(e, fs.ErrNotExist) {
+		return nil
+	}
+	if e := loadJSONFile(testbedEndpointsFile, &m); e != nil {
+		testbedLogger.Warn("load endpoints", zap.Error(e))
+		return nil
+	}
+	return m
+}
+
 func fetchTestbedNodes() (m map[string]testbedNode) {
 	response, e := http.Get(testbedNodesURI)
 	if e != nil {
@@ -166,6 +228,12 @@ func fetchTestbedNodes() (m map[string]testbedNode) {
 	return m
 }
 
+func listTestbedRouters() []model.Router {
+	testbedRoutersLock.RLock()
+	defer testbedRoutersLock.RUnlock()
+	return slices.Clone(testbedRouters)
+}
+
 func updateTestbedRouters() {
 	time.AfterFunc(time.Duration(600+rand.Intn(60))*time.Second, updateTestbedRouters)
 
@@ -181,8 +249,10 @@ func updateTestbedRouters() {
 		}
 	}
 
+	endpoints := loadTestbedEndpoints()
 	routers := []model.Router{}
 	for _, n := range nodes {
+		n.applyEndpoints(endpoints[n.ShortName])
 		r := n.Router()
 		if r != nil {
 			routers = append(routers, *r)
@@ -0,0 +1,35 @@
+package routerlist
+
+import (
+	"testing"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTestbedEndpoints(t *testing.T) {
+	assert := assert.New(t)
+
+	n := testbedNode{
+		ShortName:   "EXAMPLE",
+		Site:        "https://router.example.net/",
+		IPAddresses: []string{"192.0.2.1"},
+		Position:    []float64{31.2304, 121.4737},
+		Prefix:      "ndn:/ndn/example",
+		Endpoints: testbedEndpoints{
+			model.TransportWebSocket: "wss://router.example.net:8443/ndn/",
+		},
+	}
+	n.applyEndpoints(testbedEndpoints{
+		model.TransportH3: "https://quic.example.net:6367/ndn",
+	})
+	r := n.Router()
+	if !assert.NotNil(r) {
+		return
+	}
+
+	assert.Equal("router.example.net:6363", r.ConnectString(model.TransportIPFamily{Transport: model.TransportUDP, Family: model.IPv4}))
+	assert.Equal("wss://router.example.net:8443/ndn/", r.ConnectString(model.TransportIPFamily{Transport: model.TransportWebSocket, Family: model.IPv4}))
+	assert.Equal("https://quic.example.net:6367/ndn", r.ConnectString(model.TransportIPFamily{Transport: model.TransportH3, Family: model.IPv4}))
+	assert.Equal("", r.ConnectString(model.TransportIPFamily{Transport: model.TransportH3, Family: model.IPv6}))
+}
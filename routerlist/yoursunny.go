@@ -2,6 +2,7 @@ package routerlist
 
 import (
 	"fmt"
+	"maps"
 	"slices"
 	"strings"
 
@@ -31,8 +32,9 @@ type ndn6Node struct {
 	id       string
 	allLinks map[string]int
 
-	PositionV model.LonLat `json:"position"`
-	Public    []string     `json:"public"`
+	PositionV model.LonLat      `json:"position"`
+	Public    []string          `json:"public"`
+	TagsV     map[string]string `json:"tags,omitempty"`
 
 	Links []struct {
 		ID   string `json:"remote_id"`
@@ -40,7 +42,10 @@ type ndn6Node struct {
 	} `json:"links"`
 }
 
-var _ model.Router = ndn6Node{}
+var (
+	_ model.Router     = ndn6Node{}
+	_ positionedRouter = ndn6Node{}
+)
 
 func (n ndn6Node) ID() string {
 	return n.id
@@ -50,6 +55,10 @@ func (n ndn6Node) Position() (pos model.LonLat) {
 	return n.PositionV
 }
 
+func (n ndn6Node) HasPosition() bool {
+	return n.PositionV != model.LonLat{}
+}
+
 func (r ndn6Node) Prefix() string {
 	return r.topo.Site + "/" + r.id
 }
@@ -72,12 +81,24 @@ func (r ndn6Node) Neighbors() (links map[string]int) {
 	return r.allLinks
 }
 
+func (r ndn6Node) Tags() (tags map[string]string) {
+	tags = map[string]string{}
+	maps.Copy(tags, r.TagsV)
+	tags[model.TagSource] = SourceNDN6
+	tags["network"] = r.topo.Network
+	return tags
+}
+
 type ndn6Link struct {
 	Src  string `json:"src"`
 	Dst  string `json:"dst"`
 	Cost int    `json:"cost"`
 }
 
+func listNDN6Routers() []model.Router {
+	return slices.Clone(ndn6Routers)
+}
+
 func loadNDN6Topo() {
 	var topo ndn6Topo
 	if e := loadJSONFile(ndn6TopoFilename, &topo); e != nil {
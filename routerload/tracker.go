@@ -0,0 +1,115 @@
+// Package routerload tracks how often each router appears in query responses.
+package routerload
+
+import (
+	"sync"
+	"time"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+)
+
+// MinShareTotal is the minimum number of responses in the window before MaxShare is enforced.
+var MinShareTotal = 100
+
+type bucket struct {
+	start  time.Time
+	total  int
+	counts map[string]int
+}
+
+// Tracker counts query responses per router over a sliding window.
+type Tracker struct {
+	mutex     sync.Mutex
+	window    time.Duration
+	bucketDur time.Duration
+	buckets   []bucket
+	now       func() time.Time
+}
+
+var _ model.LoadChecker = &Tracker{}
+
+// NewTracker creates a Tracker with a sliding window divided into nBuckets.
+// window is raised to at least one nanosecond per bucket.
+func NewTracker(window time.Duration, nBuckets int) *Tracker {
+	nBuckets = max(1, nBuckets)
+	window = max(window, time.Duration(nBuckets))
+	return &Tracker{
+		window:    window,
+		bucketDur: window / time.Duration(nBuckets),
+		buckets:   make([]bucket, nBuckets),
+		now:       time.Now,
+	}
+}
+
+// current returns the bucket for the current time, resetting it if stale.
+func (t *Tracker) current() *bucket {
+	now := t.now()
+	start := now.Truncate(t.bucketDur)
+	b := &t.buckets[int(start.UnixNano()/int64(t.bucketDur))%len(t.buckets)]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start, counts: map[string]int{}}
+	}
+	return b
+}
+
+// Record records one query response that includes the given routers.
+func (t *Tracker) Record(ids ...string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	b := t.current()
+	b.total++
+	for _, id := range ids {
+		b.counts[id]++
+	}
+}
+
+func (t *Tracker) sum(id string) (count, total int) {
+	oldest := t.now().Add(-t.window)
+	for _, b := range t.buckets {
+		if b.start.After(oldest) {
+			count += b.counts[id]
+			total += b.total
+		}
+	}
+	return
+}
+
+// Counts returns number of responses per router and total number of responses in the window.
+func (t *Tracker) Counts() (counts map[string]int, total int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	counts = map[string]int{}
+	oldest := t.now().Add(-t.window)
+	for _, b := range t.buckets {
+		if !b.start.After(oldest) {
+			continue
+		}
+		total += b.total
+		for id, n := range b.counts {
+			counts[id] += n
+		}
+	}
+	return counts, total
+}
+
+// Saturated implements model.LoadChecker interface.
+func (t *Tracker) Saturated(r model.Router) bool {
+	c := model.RouterCapacity(r)
+	if c.MaxShare <= 0 && c.MaxRate <= 0 {
+		return false
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	count, total := t.sum(r.ID())
+
+	if c.MaxShare > 0 && total >= MinShareTotal && float64(count) >= c.MaxShare*float64(total) {
+		return true
+	}
+	if c.MaxRate > 0 && float64(count) >= c.MaxRate*t.window.Minutes() {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package routerload
+
+import (
+	"testing"
+	"time"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type testRouter struct {
+	model.Router
+	id       string
+	capacity model.Capacity
+}
+
+func (r testRouter) ID() string {
+	return r.id
+}
+
+func (r testRouter) Capacity() model.Capacity {
+	return r.capacity
+}
+
+func TestTracker(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Unix(1700000000, 0)
+	tracker := NewTracker(10*time.Minute, 10)
+	tracker.now = func() time.Time { return now }
+
+	small := testRouter{id: "small", capacity: model.Capacity{MaxShare: 0.25}}
+	slow := testRouter{id: "slow", capacity: model.Capacity{MaxRate: 1}}
+	big := testRouter{id: "big"}
+
+	for i := range 200 {
+		ids := []string{"big"}
+		if i%2 == 0 {
+			ids = append(ids, "small")
+		}
+		if i < 5 {
+			ids = append(ids, "slow")
+		}
+		tracker.Record(ids...)
+	}
+
+	counts, total := tracker.Counts()
+	assert.Equal(200, total)
+	assert.Equal(200, counts["big"])
+	assert.Equal(100, counts["small"])
+	assert.Equal(5, counts["slow"])
+
+	assert.False(tracker.Saturated(big))
+	assert.True(tracker.Saturated(small))
+	assert.False(tracker.Saturated(slow))
+
+	for range 5 {
+		tracker.Record("slow")
+	}
+	assert.True(tracker.Saturated(slow))
+
+	now = now.Add(11 * time.Minute)
+	_, total = tracker.Counts()
+	assert.Zero(total)
+	assert.False(tracker.Saturated(small))
+	assert.False(tracker.Saturated(slow))
+
+	tiny := NewTracker(0, 10)
+	tiny.Record("big")
+	assert.False(tiny.Saturated(big))
+}
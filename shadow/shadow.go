@@ -0,0 +1,175 @@
+// Package shadow compares alternative rankers against production query answers.
+package shadow
+
+import (
+	"math"
+	"sync"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/logging"
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"go.uber.org/zap"
+)
+
+var logger = logging.New("shadow")
+
+// Stats contains aggregated comparison between a shadow ranker and production.
+type Stats struct {
+	Ranker  model.RankMode `json:"ranker"`
+	Queries int            `json:"queries"`
+
+	// Top1Agree is the fraction of queries where both rankers chose the same first router.
+	Top1Agree float64 `json:"top1Agree"`
+
+	// RankCorrelation is the mean Spearman rank correlation of the first Depth returned routers.
+	RankCorrelation float64 `json:"rankCorrelation"`
+
+	// DistanceDelta is the mean distance of shadow first router minus production first router, in kilometers.
+	DistanceDelta float64 `json:"distanceDelta"`
+	// AbsDistanceDelta is the mean absolute value of DistanceDelta.
+	AbsDistanceDelta float64 `json:"absDistanceDelta"`
+
+	sumTop1, sumCorr, sumDelta, sumAbsDelta float64
+	nCorr                                   int
+}
+
+func (s *Stats) add(prod, shadow []model.ScoredRouter, pos model.LonLat) {
+	s.Queries++
+	if len(prod) > 0 && len(shadow) > 0 {
+		if prod[0].ID() == shadow[0].ID() {
+			s.sumTop1++
+		}
+		delta := model.Distance(pos, shadow[0].Position()) - model.Distance(pos, prod[0].Position())
+		s.sumDelta += delta
+		s.sumAbsDelta += math.Abs(delta)
+	}
+	if corr, ok := spearman(prod, shadow); ok {
+		s.sumCorr += corr
+		s.nCorr++
+	}
+
+	n := float64(s.Queries)
+	s.Top1Agree, s.DistanceDelta, s.AbsDistanceDelta = s.sumTop1/n, s.sumDelta/n, s.sumAbsDelta/n
+	if s.nCorr > 0 {
+		s.RankCorrelation = s.sumCorr / float64(s.nCorr)
+	}
+}
+
+// spearman computes Spearman rank correlation over the union of two rankings.
+// A router missing from one ranking is assigned the rank after the last entry.
+func spearman(a, b []model.ScoredRouter) (rho float64, ok bool) {
+	rankA, rankB := map[string]int{}, map[string]int{}
+	var ids []string
+	for i, r := range a {
+		rankA[r.ID()] = i
+		ids = append(ids, r.ID())
+	}
+	for i, r := range b {
+		rankB[r.ID()] = i
+		if _, ok := rankA[r.ID()]; !ok {
+			ids = append(ids, r.ID())
+		}
+	}
+
+	n := float64(len(ids))
+	if n < 2 {
+		return 0, false
+	}
+	sumD2 := 0.0
+	for _, id := range ids {
+		ra, ok := rankA[id]
+		if !ok {
+			ra = len(a)
+		}
+		rb, ok := rankB[id]
+		if !ok {
+			rb = len(b)
+		}
+		d := float64(ra - rb)
+		sumD2 += d * d
+	}
+	return 1 - 6*sumD2/(n*(n*n-1)), true
+}
+
+// Evaluator runs shadow rankers alongside production queries.
+type Evaluator struct {
+	// Depth is the maximum number of returned routers compared for rank correlation.
+	Depth int
+
+	rankers []model.RankMode
+	sem     chan struct{}
+	mutex   sync.Mutex
+	stats   map[model.RankMode]*Stats
+}
+
+// NewEvaluator creates an Evaluator for the given shadow rankers.
+// At most concurrency evaluations run at the same time; further queries are not evaluated.
+func NewEvaluator(rankers []model.RankMode, concurrency int) *Evaluator {
+	ev := &Evaluator{
+		Depth:   5,
+		rankers: rankers,
+		sem:     make(chan struct{}, max(1, concurrency)),
+		stats:   map[model.RankMode]*Stats{},
+	}
+	for _, name := range rankers {
+		ev.stats[name] = &Stats{Ranker: name}
+	}
+	return ev
+}
+
+// Submit evaluates shadow rankers for a query in the background.
+// served is the production answer returned to the client; it is not modified.
+func (ev *Evaluator) Submit(q model.Query, served []model.ScoredRouter, avail []model.RouterAvail) {
+	if ev == nil || len(ev.rankers) == 0 {
+		return
+	}
+	select {
+	case ev.sem <- struct{}{}:
+	default:
+		return
+	}
+	go func() {
+		defer func() { <-ev.sem }()
+		ev.Evaluate(q, served, avail)
+	}()
+}
+
+// Evaluate compares shadow rankers with the production answer served for a query.
+// Shadow rankers execute the same query, including its load checker, which is only read.
+func (ev *Evaluator) Evaluate(q model.Query, served []model.ScoredRouter, avail []model.RouterAvail) {
+	q.Explain = false
+	prod := served[:min(len(served), ev.Depth)]
+
+	for _, name := range ev.rankers {
+		sq := q
+		sq.Rank = name
+		shadow := sq.Execute(avail).Routers
+		shadow = shadow[:min(len(shadow), ev.Depth)]
+
+		if len(prod) > 0 && len(shadow) > 0 && prod[0].ID() != shadow[0].ID() {
+			logger.Debug("top1 differs",
+				zap.String("production", string(q.Rank)),
+				zap.String("shadow", string(name)),
+				zap.String("production-router", prod[0].ID()),
+				zap.String("shadow-router", shadow[0].ID()),
+			)
+		}
+
+		ev.mutex.Lock()
+		ev.stats[name].add(prod, shadow, q.Position)
+		ev.mutex.Unlock()
+	}
+}
+
+// Stats returns aggregated statistics of each shadow ranker.
+func (ev *Evaluator) Stats() (list []Stats) {
+	if ev == nil {
+		return []Stats{}
+	}
+	ev.mutex.Lock()
+	defer ev.mutex.Unlock()
+	list = []Stats{}
+	for _, name := range ev.rankers {
+		list = append(list, *ev.stats[name])
+	}
+	return list
+}
@@ -0,0 +1,92 @@
+package shadow
+
+import (
+	"testing"
+
+	"github.com/11th-ndn-hackathon/ndn-fch/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type testRouter struct {
+	id  string
+	pos model.LonLat
+}
+
+func (r testRouter) ID() string                                   { return r.id }
+func (r testRouter) Position() model.LonLat                       { return r.pos }
+func (r testRouter) Prefix() string                               { return "/" + r.id }
+func (r testRouter) ConnectString(model.TransportIPFamily) string { return r.id + ":6363" }
+func (r testRouter) Neighbors() map[string]int                    { return nil }
+func (r testRouter) Tags() map[string]string                      { return nil }
+
+func scored(ids ...string) (list []model.ScoredRouter) {
+	for _, id := range ids {
+		list = append(list, model.ScoredRouter{RouterAvail: model.RouterAvail{Router: testRouter{id: id}}})
+	}
+	return list
+}
+
+func TestSpearman(t *testing.T) {
+	assert := assert.New(t)
+
+	rho, ok := spearman(scored("A", "B", "C"), scored("A", "B", "C"))
+	assert.True(ok)
+	assert.InDelta(1.0, rho, 1e-9)
+
+	rho, ok = spearman(scored("A", "B", "C"), scored("C", "B", "A"))
+	assert.True(ok)
+	assert.InDelta(-1.0, rho, 1e-9)
+
+	rho, ok = spearman(scored("A", "B"), scored("A", "C"))
+	assert.True(ok)
+	assert.Less(rho, 1.0)
+
+	_, ok = spearman(scored("A"), scored("A"))
+	assert.False(ok)
+}
+
+func TestEvaluator(t *testing.T) {
+	assert := assert.New(t)
+
+	udp4 := model.TransportIPFamily{Transport: model.TransportUDP, Family: model.IPv4}
+	var avail []model.RouterAvail
+	for i, id := range []string{"A", "B", "C"} {
+		avail = append(avail, model.RouterAvail{
+			Router:    testRouter{id: id, pos: model.LonLat{float64(i * 10), 0}},
+			Available: map[model.TransportIPFamily]bool{udp4: true},
+		})
+	}
+	q := model.Query{Count: 2, Transport: model.TransportUDP, IPv4: true, Rank: model.RankDistance}
+
+	ev := NewEvaluator([]model.RankMode{model.RankDistance, model.RankRandom}, 1)
+	ev.Evaluate(q, q.Execute(avail).Routers, avail)
+	q.Position = model.LonLat{20, 0}
+	ev.Evaluate(q, q.Execute(avail).Routers, avail)
+
+	stats := ev.Stats()
+	if assert.Len(stats, 2) {
+		assert.Equal(model.RankDistance, stats[0].Ranker)
+		assert.Equal(2, stats[0].Queries)
+		assert.InDelta(1.0, stats[0].Top1Agree, 1e-9)
+		assert.InDelta(1.0, stats[0].RankCorrelation, 1e-9)
+		assert.InDelta(0.0, stats[0].AbsDistanceDelta, 1e-9)
+
+		assert.Equal(model.RankRandom, stats[1].Ranker)
+		assert.Equal(2, stats[1].Queries)
+		assert.GreaterOrEqual(stats[1].DistanceDelta, 0.0)
+	}
+
+	// comparison is against the served answer, not a re-execution of the production ranker
+	ev = NewEvaluator([]model.RankMode{model.RankDistance}, 1)
+	q.Position = model.LonLat{0, 0}
+	ev.Evaluate(q, []model.ScoredRouter{{RouterAvail: avail[2]}, {RouterAvail: avail[1]}}, avail)
+	stats = ev.Stats()
+	if assert.Len(stats, 1) {
+		assert.InDelta(0.0, stats[0].Top1Agree, 1e-9)
+		assert.InDelta(-20*111.19, stats[0].DistanceDelta, 20)
+	}
+
+	var nilEvaluator *Evaluator
+	nilEvaluator.Submit(q, nil, avail)
+	assert.Empty(nilEvaluator.Stats())
+}